@@ -6,6 +6,9 @@ require (
 	github.com/go-chi/chi v1.5.5
 	github.com/go-chi/cors v1.2.1
 	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/lib/pq v1.12.3
 	golang.org/x/crypto v0.17.0
 	modernc.org/sqlite v1.32.0
 )
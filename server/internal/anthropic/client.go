@@ -0,0 +1,392 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/usage"
+)
+
+type Anthropic struct {
+	apiKey    string
+	baseURL   string
+	chatModel string
+	maxTokens int
+
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+type Option func(*Anthropic)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Anthropic) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Anthropic) {
+		c.timeout = timeout
+	}
+}
+
+const (
+	providerName = "anthropic"
+	baseURL      = "https://api.anthropic.com/v1"
+	apiVersion   = "2023-06-01"
+	chatModel    = "claude-3-5-sonnet-latest"
+	maxTokens    = 1024
+)
+
+// continuePrompt is sent as the first user turn when the conversation starts
+// with the interviewer greeting, since the messages API requires the first
+// message to come from the user.
+const continuePrompt = "Please start the interview."
+
+func NewAnthropic(apiKey, baseURLOverride, chatModelOverride string, opts ...Option) *Anthropic {
+	client := &Anthropic{
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		chatModel:  chatModel,
+		maxTokens:  maxTokens,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if baseURLOverride != "" {
+		client.baseURL = baseURLOverride
+	}
+
+	if chatModelOverride != "" {
+		client.chatModel = chatModelOverride
+	}
+
+	return client
+}
+
+func (c *Anthropic) IsKeyValid(ctx context.Context) (bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	url, err := url.JoinPath(c.baseURL, "/models")
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (c *Anthropic) Chat(ctx context.Context, messages []openai.ChatMessage) (string, error) {
+	return c.createMessage(ctx, convertMessages(messages))
+}
+
+func (c *Anthropic) ChatStream(ctx context.Context, messages []openai.ChatMessage, onDelta func(string) error) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	msgReq := convertMessages(messages)
+	msgReq.Model = c.chatModel
+	msgReq.MaxTokens = c.maxTokens
+	msgReq.Stream = true
+
+	resp, err := c.post(ctx, "/messages", msgReq)
+	if err != nil {
+		return "", err
+	}
+
+	respBody, err := getResponseBody(resp)
+	if err != nil {
+		return "", err
+	}
+	defer respBody.Close()
+
+	var text strings.Builder
+	var streamUsage Usage
+	defer func() { c.recordUsage(ctx, streamUsage) }()
+
+	scanner := bufio.NewScanner(respBody)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return "", err
+		}
+
+		switch event.Type {
+		case EVENT_MESSAGE_START:
+			streamUsage.InputTokens = event.Message.Usage.InputTokens
+		case EVENT_MESSAGE_DELTA:
+			streamUsage.OutputTokens = event.Usage.OutputTokens
+		case EVENT_CONTENT_BLOCK_DELTA:
+			if event.Delta.Text == "" {
+				continue
+			}
+
+			text.WriteString(event.Delta.Text)
+
+			if err := onDelta(event.Delta.Text); err != nil {
+				return "", err
+			}
+		case EVENT_ERROR:
+			return "", apierror.FromDetail(providerName, resp.StatusCode, apierror.Detail{
+				Type:    event.Error.Type,
+				Message: event.Error.Message,
+			})
+		}
+
+		if event.Type == EVENT_MESSAGE_STOP {
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no valid response returned")
+	}
+
+	return text.String(), nil
+}
+
+func (c *Anthropic) SSML(ctx context.Context, text string) (string, error) {
+	return c.createMessage(ctx, MessageRequest{
+		System: openai.GetSSMLPrompt(),
+		Messages: []Message{
+			{
+				Role:    ROLE_USER,
+				Content: text,
+			},
+		},
+	})
+}
+
+// ChatJSON forces the model to call a tool whose input schema is the given
+// schema, and returns that input.
+func (c *Anthropic) ChatJSON(ctx context.Context, messages []openai.ChatMessage, schema openai.JSONSchema) (string, error) {
+	msgReq := convertMessages(messages)
+	msgReq.Tools = []Tool{
+		{
+			Name:        schema.Name,
+			Description: "Record the result in the required structure.",
+			InputSchema: schema.Schema,
+		},
+	}
+	msgReq.ToolChoice = &ToolChoice{
+		Type: "tool",
+		Name: schema.Name,
+	}
+
+	msgResp, err := c.sendMessage(ctx, msgReq)
+	if err != nil {
+		return "", err
+	}
+
+	for _, content := range msgResp.Content {
+		if content.Type == CONTENT_TOOL_USE && len(content.Input) > 0 {
+			return string(content.Input), nil
+		}
+	}
+
+	return "", fmt.Errorf("no valid response returned")
+}
+
+func (c *Anthropic) createMessage(ctx context.Context, msgReq MessageRequest) (string, error) {
+	msgResp, err := c.sendMessage(ctx, msgReq)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, content := range msgResp.Content {
+		if content.Type == CONTENT_TEXT {
+			text.WriteString(content.Text)
+		}
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no valid response returned")
+	}
+
+	return text.String(), nil
+}
+
+func (c *Anthropic) sendMessage(ctx context.Context, msgReq MessageRequest) (MessageResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	msgReq.Model = c.chatModel
+	msgReq.MaxTokens = c.maxTokens
+
+	resp, err := c.post(ctx, "/messages", msgReq)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	var msgResp MessageResponse
+	err = unmarshalJSONResponse(resp, &msgResp)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	c.recordUsage(ctx, msgResp.Usage)
+
+	return msgResp, nil
+}
+
+func (c *Anthropic) post(ctx context.Context, path string, payload any) (*http.Response, error) {
+	url, err := url.JoinPath(c.baseURL, path)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.httpClient.Do(req)
+}
+
+func (c *Anthropic) recordUsage(ctx context.Context, u Usage) {
+	usage.Add(ctx, usage.Record{
+		Kind:         usage.KIND_CHAT,
+		Provider:     providerName,
+		Model:        c.chatModel,
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+	})
+}
+
+func (c *Anthropic) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *Anthropic) setHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+}
+
+// convertMessages maps an OpenAI style history to the messages API format.
+// System messages are lifted into the top-level system prompt, consecutive
+// messages of the same role are merged, and a user turn is prepended when
+// the history starts with the assistant.
+func convertMessages(messages []openai.ChatMessage) MessageRequest {
+	var system []string
+	var converted []Message
+
+	for _, message := range messages {
+		if message.Role == openai.ROLE_SYSTEM {
+			system = append(system, message.Content)
+			continue
+		}
+
+		role := ROLE_USER
+		if message.Role == openai.ROLE_ASSISTANT {
+			role = ROLE_ASSISTANT
+		}
+
+		if len(converted) == 0 && role == ROLE_ASSISTANT {
+			converted = append(converted, Message{Role: ROLE_USER, Content: continuePrompt})
+		}
+
+		if last := len(converted) - 1; last >= 0 && converted[last].Role == role {
+			converted[last].Content += "\n\n" + message.Content
+			continue
+		}
+
+		converted = append(converted, Message{Role: role, Content: message.Content})
+	}
+
+	return MessageRequest{
+		System:   strings.Join(system, "\n\n"),
+		Messages: converted,
+	}
+}
+
+func getResponseBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp == nil || resp.Body == nil {
+		return nil, fmt.Errorf("response is nil")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(providerName, resp, parseError)
+	}
+
+	return resp.Body, nil
+}
+
+func parseError(body []byte) apierror.Detail {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return apierror.Detail{}
+	}
+
+	return apierror.Detail{
+		Type:    errResp.Error.Type,
+		Message: errResp.Error.Message,
+	}
+}
+
+func unmarshalJSONResponse(resp *http.Response, v interface{}) error {
+	respBody, err := getResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	defer respBody.Close()
+
+	respByte, err := io.ReadAll(respBody)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(respByte, v)
+}
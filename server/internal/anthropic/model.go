@@ -0,0 +1,84 @@
+package anthropic
+
+import "encoding/json"
+
+type MessageRequest struct {
+	Model     string    `json:"model"`
+	System    string    `json:"system,omitempty"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens"`
+	Stream    bool      `json:"stream,omitempty"`
+
+	Tools      []Tool      `json:"tools,omitempty"`
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
+}
+
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type MessageResponse struct {
+	Content    []Content `json:"content"`
+	StopReason string    `json:"stop_reason"`
+	Usage      Usage     `json:"usage"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type Content struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Input json.RawMessage `json:"input"`
+}
+
+type StreamEvent struct {
+	Type    string          `json:"type"`
+	Delta   Delta           `json:"delta"`
+	Error   ErrorDetail     `json:"error"`
+	Message MessageResponse `json:"message"`
+	Usage   Usage           `json:"usage"`
+}
+
+type Delta struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type ErrorResponse struct {
+	Type  string      `json:"type"`
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+const (
+	ROLE_USER      = "user"
+	ROLE_ASSISTANT = "assistant"
+
+	CONTENT_TEXT     = "text"
+	CONTENT_TOOL_USE = "tool_use"
+
+	EVENT_MESSAGE_START       = "message_start"
+	EVENT_CONTENT_BLOCK_DELTA = "content_block_delta"
+	EVENT_MESSAGE_DELTA       = "message_delta"
+	EVENT_MESSAGE_STOP        = "message_stop"
+	EVENT_ERROR               = "error"
+)
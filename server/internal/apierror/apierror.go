@@ -0,0 +1,212 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Kind string
+
+const (
+	KIND_RATE_LIMIT      Kind = "rate_limit"
+	KIND_QUOTA           Kind = "quota"
+	KIND_AUTH            Kind = "auth"
+	KIND_SERVER          Kind = "server"
+	KIND_INVALID_REQUEST Kind = "invalid_request"
+	KIND_UNAVAILABLE     Kind = "unavailable"
+	KIND_UNKNOWN         Kind = "unknown"
+)
+
+// Error is a failed call to an upstream AI provider. StatusCode is zero
+// when the call was never made.
+type Error struct {
+	Provider   string
+	StatusCode int
+	Kind       Kind
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %s: %s", e.Provider, e.Kind, e.Message)
+	}
+
+	if e.Message == "" {
+		return fmt.Sprintf("%s: unexpected status code: %d (%s)", e.Provider, e.StatusCode, e.Kind)
+	}
+
+	return fmt.Sprintf("%s: unexpected status code: %d (%s): %s", e.Provider, e.StatusCode, e.Kind, e.Message)
+}
+
+// Retryable reports whether the same call may succeed later.
+func (e *Error) Retryable() bool {
+	return e.Kind == KIND_RATE_LIMIT || e.Kind == KIND_SERVER || e.Kind == KIND_UNAVAILABLE
+}
+
+// Detail is what a provider's error body tells about the failure.
+type Detail struct {
+	Code    string
+	Type    string
+	Message string
+}
+
+// Parser extracts the error detail from a provider's error body.
+type Parser func(body []byte) Detail
+
+// FromResponse builds an Error from a non-successful response, consuming
+// and closing its body.
+func FromResponse(provider string, resp *http.Response, parse Parser) *Error {
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var detail Detail
+	if parse != nil && len(body) > 0 {
+		detail = parse(body)
+	}
+
+	apiErr := FromDetail(provider, resp.StatusCode, detail)
+	apiErr.RetryAfter = ParseRetryAfter(resp.Header)
+
+	return apiErr
+}
+
+// FromDetail builds an Error reported outside of the status line, such as
+// an error event in the middle of a stream.
+func FromDetail(provider string, statusCode int, detail Detail) *Error {
+	return &Error{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Kind:       classify(statusCode, detail),
+		Code:       firstNonEmpty(detail.Code, detail.Type),
+		Message:    detail.Message,
+	}
+}
+
+// Unavailable is returned without calling the provider, e.g. while its
+// circuit breaker is open.
+func Unavailable(provider string, retryAfter time.Duration) *Error {
+	return &Error{
+		Provider:   provider,
+		Kind:       KIND_UNAVAILABLE,
+		Message:    "temporarily unavailable after repeated failures",
+		RetryAfter: retryAfter,
+	}
+}
+
+func KindOf(err error) Kind {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+
+	return KIND_UNKNOWN
+}
+
+var quotaCodes = map[string]struct{}{
+	"insufficient_quota": {},
+	"quota_exceeded":     {},
+	"billing_error":      {},
+}
+
+var rateLimitCodes = map[string]struct{}{
+	"rate_limit_exceeded":          {},
+	"rate_limit_error":             {},
+	"too_many_concurrent_requests": {},
+	"system_busy":                  {},
+}
+
+var authCodes = map[string]struct{}{
+	"invalid_api_key":           {},
+	"authentication_error":      {},
+	"permission_error":          {},
+	"detected_unusual_activity": {},
+}
+
+var serverCodes = map[string]struct{}{
+	"server_error":     {},
+	"api_error":        {},
+	"overloaded_error": {},
+}
+
+// classify prefers the provider's own error code over the status code since
+// e.g. both an exhausted quota and a rate limit come back as 429.
+func classify(statusCode int, detail Detail) Kind {
+	for _, code := range []string{detail.Code, detail.Type} {
+		if code == "" {
+			continue
+		}
+
+		if _, ok := quotaCodes[code]; ok {
+			return KIND_QUOTA
+		}
+
+		if _, ok := rateLimitCodes[code]; ok {
+			return KIND_RATE_LIMIT
+		}
+
+		if _, ok := authCodes[code]; ok {
+			return KIND_AUTH
+		}
+
+		if _, ok := serverCodes[code]; ok {
+			return KIND_SERVER
+		}
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return KIND_RATE_LIMIT
+	case statusCode == http.StatusPaymentRequired:
+		return KIND_QUOTA
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return KIND_AUTH
+	case statusCode >= http.StatusInternalServerError:
+		return KIND_SERVER
+	case statusCode >= http.StatusBadRequest:
+		return KIND_INVALID_REQUEST
+	}
+
+	return KIND_UNKNOWN
+}
+
+// ParseRetryAfter reads Retry-After as seconds or an HTTP date, and the
+// millisecond variant some providers send.
+func ParseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,93 @@
+package apierror
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		detail     Detail
+		want       Kind
+	}{
+		{"rate limit", http.StatusTooManyRequests, Detail{}, KIND_RATE_LIMIT},
+		{"quota behind 429", http.StatusTooManyRequests, Detail{Code: "insufficient_quota"}, KIND_QUOTA},
+		{"anthropic overloaded", 529, Detail{Type: "overloaded_error"}, KIND_SERVER},
+		{"anthropic rate limit", http.StatusTooManyRequests, Detail{Type: "rate_limit_error"}, KIND_RATE_LIMIT},
+		{"payment required", http.StatusPaymentRequired, Detail{}, KIND_QUOTA},
+		{"unauthorized", http.StatusUnauthorized, Detail{}, KIND_AUTH},
+		{"forbidden", http.StatusForbidden, Detail{}, KIND_AUTH},
+		{"invalid key behind 400", http.StatusBadRequest, Detail{Code: "invalid_api_key"}, KIND_AUTH},
+		{"server", http.StatusBadGateway, Detail{}, KIND_SERVER},
+		{"bad request", http.StatusBadRequest, Detail{Code: "context_length_exceeded"}, KIND_INVALID_REQUEST},
+		{"stream error", 0, Detail{Code: "server_error"}, KIND_SERVER},
+		{"unknown", 0, Detail{}, KIND_UNKNOWN},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classify(test.statusCode, test.detail); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for kind, want := range map[Kind]bool{
+		KIND_RATE_LIMIT:      true,
+		KIND_SERVER:          true,
+		KIND_UNAVAILABLE:     true,
+		KIND_QUOTA:           false,
+		KIND_AUTH:            false,
+		KIND_INVALID_REQUEST: false,
+		KIND_UNKNOWN:         false,
+	} {
+		if got := (&Error{Kind: kind}).Retryable(); got != want {
+			t.Errorf("%s: got retryable %v, want %v", kind, got, want)
+		}
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {"2"}},
+		Body:       io.NopCloser(strings.NewReader(`{"error":{"code":"rate_limit_exceeded"}}`)),
+	}
+
+	apiErr := FromResponse("openai", resp, func(body []byte) Detail {
+		return Detail{Code: "rate_limit_exceeded", Message: string(body)}
+	})
+
+	if apiErr.Kind != KIND_RATE_LIMIT || apiErr.Code != "rate_limit_exceeded" || apiErr.RetryAfter != 2*time.Second {
+		t.Errorf("got %+v, want a rate limit retrying after 2s", apiErr)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"seconds", http.Header{"Retry-After": {"3"}}, 3 * time.Second},
+		{"milliseconds", http.Header{"Retry-After": {"3"}, "Retry-After-Ms": {"250"}}, 250 * time.Millisecond},
+		{"past date", http.Header{"Retry-After": {"Wed, 21 Oct 2015 07:28:00 GMT"}}, 0},
+		{"garbage", http.Header{"Retry-After": {"soon"}}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseRetryAfter(test.header); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps binary content, such as audio, by key. Keys are slash
+// separated paths with the file extension of the content.
+type Store interface {
+	Put(ctx context.Context, key, contentType string, content []byte) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Object is stored content, seekable so it can be served in ranges. The
+// caller closes it.
+type Object struct {
+	io.ReadSeekCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// extensions covers the audio types the standard library does not know.
+var extensions = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".webm": "audio/webm",
+	".m4a":  "audio/mp4",
+	".flac": "audio/flac",
+}
+
+// Extension returns the file extension for a content type, ".bin" when
+// unknown.
+func Extension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for extension, known := range extensions {
+		if known == mediaType {
+			return extension
+		}
+	}
+
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+
+	return ".bin"
+}
+
+// ContentType guesses the content type of a key from its extension.
+func ContentType(key string) string {
+	extension := strings.ToLower(path.Ext(key))
+	if contentType, ok := extensions[extension]; ok {
+		return contentType
+	}
+
+	if contentType := mime.TypeByExtension(extension); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// validKey rejects keys that could leave the store's root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,174 @@
+package budget
+
+import (
+	"context"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/usage"
+)
+
+const (
+	CODE_TURNS_EXHAUSTED  = "turn_budget_exhausted"
+	CODE_AUDIO_EXHAUSTED  = "audio_budget_exhausted"
+	CODE_TOKENS_EXHAUSTED = "token_budget_exhausted"
+	CODE_SPEND_EXHAUSTED  = "daily_spend_exhausted"
+)
+
+var messages = map[string]string{
+	CODE_TURNS_EXHAUSTED:  "this interview has reached its maximum number of answers",
+	CODE_AUDIO_EXHAUSTED:  "this interview has reached its maximum recorded audio",
+	CODE_TOKENS_EXHAUSTED: "this interview has reached its maximum tokens",
+	CODE_SPEND_EXHAUSTED:  "the daily spending limit has been reached, try again tomorrow",
+}
+
+// Limits of zero are not enforced.
+type Limits struct {
+	MaxTurns        int
+	MaxAudioSeconds float64
+	MaxTokens       int
+	// MaxDailySpend is in US dollars per client and UTC day
+	MaxDailySpend float64
+}
+
+// Error is returned when a budget is exhausted, Code is machine-readable.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string {
+	return messages[e.Code]
+}
+
+// Checker compares the stored usage, plus the usage recorded so far on the
+// request's context, against the limits.
+type Checker struct {
+	db     data.Store
+	limits Limits
+	prices usage.PriceTable
+}
+
+func NewChecker(db data.Store, limits Limits, prices usage.PriceTable) *Checker {
+	return &Checker{
+		db:     db,
+		limits: limits,
+		prices: prices,
+	}
+}
+
+// CheckClient is checked before a new session is started.
+func (c *Checker) CheckClient(ctx context.Context, client string) error {
+	if c.limits.MaxDailySpend <= 0 {
+		return nil
+	}
+
+	spend, err := c.db.GetSpendByClient(client, startOfDay(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	for _, record := range pending(ctx) {
+		spend += c.prices.Cost(record)
+	}
+
+	if spend >= c.limits.MaxDailySpend {
+		return &Error{Code: CODE_SPEND_EXHAUSTED}
+	}
+
+	return nil
+}
+
+// CheckSession is checked before any AI call made for an existing session.
+func (c *Checker) CheckSession(ctx context.Context, user *data.ChatUser) error {
+	if c.limits.MaxTokens > 0 {
+		total, err := c.sessionTotal(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if total.InputTokens+total.OutputTokens >= c.limits.MaxTokens {
+			return &Error{Code: CODE_TOKENS_EXHAUSTED}
+		}
+	}
+
+	return c.CheckClient(ctx, user.Client)
+}
+
+// CheckTurn is checked before a new answer is transcribed, answers is the
+// number of answers the candidate has given so far and audioSeconds the
+// length of its recording, 0 when it is typed or cannot be measured before
+// it is transcribed.
+func (c *Checker) CheckTurn(ctx context.Context, user *data.ChatUser, answers int, audioSeconds float64) error {
+	if c.limits.MaxTurns > 0 && answers >= c.limits.MaxTurns {
+		return &Error{Code: CODE_TURNS_EXHAUSTED}
+	}
+
+	if c.limits.MaxAudioSeconds > 0 {
+		total, err := c.sessionTotal(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if total.AudioSeconds >= c.limits.MaxAudioSeconds || total.AudioSeconds+audioSeconds > c.limits.MaxAudioSeconds {
+			return &Error{Code: CODE_AUDIO_EXHAUSTED}
+		}
+	}
+
+	return c.CheckSession(ctx, user)
+}
+
+// CheckAudio is checked once a recording is transcribed, when its length is
+// part of the usage, so one that could not be measured before does not go
+// past the limit either.
+func (c *Checker) CheckAudio(ctx context.Context, user *data.ChatUser) error {
+	if c.limits.MaxAudioSeconds <= 0 {
+		return nil
+	}
+
+	total, err := c.sessionTotal(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if total.AudioSeconds > c.limits.MaxAudioSeconds {
+		return &Error{Code: CODE_AUDIO_EXHAUSTED}
+	}
+
+	return nil
+}
+
+func (c *Checker) sessionTotal(ctx context.Context, chatUserID string) (data.UsageTotal, error) {
+	totals, err := c.db.GetUsageTotalsByChatUserID(chatUserID)
+	if err != nil {
+		return data.UsageTotal{}, err
+	}
+
+	var sum data.UsageTotal
+	for _, total := range totals {
+		sum.InputTokens += total.InputTokens
+		sum.OutputTokens += total.OutputTokens
+		sum.AudioSeconds += total.AudioSeconds
+	}
+
+	for _, record := range pending(ctx) {
+		sum.InputTokens += record.InputTokens
+		sum.OutputTokens += record.OutputTokens
+		sum.AudioSeconds += record.AudioSeconds
+	}
+
+	return sum, nil
+}
+
+// pending is the usage of the current request that is not saved yet.
+func pending(ctx context.Context) []usage.Record {
+	recorder := usage.FromContext(ctx)
+	if recorder == nil {
+		return nil
+	}
+
+	return recorder.Records()
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
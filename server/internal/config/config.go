@@ -1,8 +1,11 @@
 package config
 
 import (
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type AppConfig struct {
@@ -11,6 +14,65 @@ type AppConfig struct {
 	TTSAPIKey string
 	DBPath    string
 
+	LLMProvider string
+	LLMAPIKey   string
+	LLMBaseURL  string
+	LLMModel    string
+
+	// SpeechProvider transcribes answers and speaks replies, it defaults to
+	// the fake one with the fake chat provider and to OpenAI otherwise
+	SpeechProvider           string
+	SpeechAPIKey             string
+	SpeechBaseURL            string
+	SpeechTranscriptionModel string
+	SpeechTTSModel           string
+
+	FakeScriptPath string
+
+	TTSRoutesPath     string
+	PersonasPath      string
+	LanguagePacksPath string
+	RubricPath        string
+
+	AITimeout  time.Duration
+	TTSTimeout time.Duration
+	// HTTPClient is used for every upstream call, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	RetryMaxAttempts int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	HistoryMaxTokens      int
+	HistoryRecentMessages int
+
+	PriceTablePath string
+	AdminAPIKey    string
+
+	BudgetMaxTurns        int
+	BudgetMaxAudioSeconds float64
+	BudgetMaxTokens       int
+	BudgetDailySpend      float64
+	// ClientAPIKeys are the X-API-Key values spending is charged to, any
+	// other key is ignored
+	ClientAPIKeys []string
+	// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For is believed
+	TrustedProxies []string
+
+	SessionTTL time.Duration
+
+	BlobStore         string
+	BlobDir           string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	// AudioURLs returns links to GET /chat/audio instead of inline audio
+	AudioURLs bool
+
 	CORSOrigins []string
 	CORSMethods []string
 	CORSHeaders []string
@@ -31,3 +93,35 @@ func GetStrings(envName string, defaultValue []string) []string {
 
 	return defaultValue
 }
+
+func GetDuration(envName string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(GetString(envName, "")); err == nil {
+		return value
+	}
+
+	return defaultValue
+}
+
+func GetFloat(envName string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(GetString(envName, ""), 64); err == nil {
+		return value
+	}
+
+	return defaultValue
+}
+
+func GetInt(envName string, defaultValue int) int {
+	if value, err := strconv.Atoi(GetString(envName, "")); err == nil {
+		return value
+	}
+
+	return defaultValue
+}
+
+func GetBool(envName string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(GetString(envName, "")); err == nil {
+		return value
+	}
+
+	return defaultValue
+}
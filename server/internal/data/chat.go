@@ -3,6 +3,7 @@ package data
 import (
 	"database/sql"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,58 +18,137 @@ type Entry struct {
 	ChatUserID string `json:"chat_user_id"`
 	Role       string `json:"role"`
 	Text       string `json:"text"`
-	Audio      string `json:"audio"`
+	// Audio is the base64 audio of entries stored before the blob store, it
+	// is only loaded by GetChat
+	Audio string `json:"audio"`
+	// AudioKey locates the audio in the blob store, for an answer it is the
+	// candidate's recording
+	AudioKey string `json:"audio_key"`
+	// AudioFormat is the content type of the recording and AudioDuration its
+	// length in seconds
+	AudioFormat   string  `json:"audio_format"`
+	AudioDuration float64 `json:"audio_duration"`
+	// Score is the rubric score of an answer as JSON, empty when not scored
+	Score string `json:"score"`
+	// Seq orders the entries of a session, starting at 1
+	Seq int `json:"seq"`
+	// CreatedAt is nil for entries stored before it was recorded
+	CreatedAt *time.Time `json:"created_at"`
 }
 
-func (d *Database) CreateChat(tx *sql.Tx, chatUserID, role, text, audio string) (*Entry, error) {
-	id := uuid.New().String()
-	_, err := tx.Exec("INSERT INTO chats (id, chat_user_id, role, text, audio) VALUES (?, ?, ?, ?, ?)",
-		id, chatUserID, role, text, audio)
+// CreateChats stores the entries in order and returns them with their IDs.
+func (t *sqlTx) CreateChats(chatUserID string, chats []Entry) ([]Entry, error) {
+	seq, err := lastSeq(t.conn, chatUserID)
 	if err != nil {
 		return nil, err
 	}
-	return &Entry{ID: id, ChatUserID: chatUserID, Role: role, Text: text, Audio: audio}, nil
-}
 
-func (d *Database) CreateChats(tx *sql.Tx, chatUserID string, chats []Entry) ([]Entry, error) {
-	query := "INSERT INTO chats (id, chat_user_id, role, text, audio) VALUES "
+	query := "INSERT INTO chats (id, chat_user_id, role, text, audio, audio_key, audio_format, audio_duration, score, seq, created_at) VALUES "
 	var values []interface{}
 	placeholders := make([]string, len(chats))
+	created := make([]Entry, len(chats))
+	now := time.Now().UTC().Truncate(time.Second)
 
 	for i, chat := range chats {
 		chat.ID = uuid.New().String()
 		chat.ChatUserID = chatUserID
+		chat.Seq = seq + i + 1
+		chat.CreatedAt = &now
+		created[i] = chat
 
-		placeholders[i] = "(?, ?, ?, ?, ?)"
+		placeholders[i] = "(?, ?, ?, ?, '', ?, ?, ?, ?, ?, ?)"
 
-		values = append(values, chat.ID, chat.ChatUserID, chat.Role, chat.Text, chat.Audio)
+		values = append(values, chat.ID, chat.ChatUserID, chat.Role, chat.Text, chat.AudioKey, chat.AudioFormat, chat.AudioDuration, chat.Score, chat.Seq, now.Format(timeLayout))
 	}
 
 	query += strings.Join(placeholders, ",")
 
-	_, err := tx.Exec(query, values...)
+	_, err = t.conn.Exec(query, values...)
 	if err != nil {
 		return nil, err
 	}
 
-	return chats, nil
+	return created, nil
+}
+
+// lastSeq returns the session's highest seq, 0 when it has no entries, once
+// no other transaction can write to the session, so concurrent answers never
+// take the same one.
+func lastSeq(tx conn, chatUserID string) (int, error) {
+	if _, err := lockChatUser(tx, chatUserID); err != nil {
+		return 0, err
+	}
+
+	var seq int
+	err := tx.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM chats WHERE chat_user_id = ?", chatUserID).Scan(&seq)
+
+	return seq, err
 }
 
+// GetChatsByChatUserID returns the entries in order without their inline
+// audio, which is only needed to serve it.
 func (d *Database) GetChatsByChatUserID(chatUserID string) ([]Entry, error) {
-	rows, err := d.conn.Query("SELECT id, chat_user_id, role, text, audio FROM chats WHERE chat_user_id = ?", chatUserID)
+	rows, err := d.conn.Query("SELECT id, chat_user_id, role, text, audio_key, audio_format, audio_duration, score, seq, created_at FROM chats WHERE chat_user_id = ? ORDER BY seq", chatUserID)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanEntries(rows)
+}
+
+// GetConversation returns a page of the entries in order, leaving out the
+// system prompt which is not part of the conversation.
+func (d *Database) GetConversation(chatUserID string, offset, limit int) ([]Entry, error) {
+	rows, err := d.conn.Query("SELECT id, chat_user_id, role, text, audio_key, audio_format, audio_duration, score, seq, created_at FROM chats WHERE chat_user_id = ? AND role <> 'system' ORDER BY seq LIMIT ? OFFSET ?", chatUserID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanEntries(rows)
+}
+
+// CountConversation counts the entries GetConversation pages through.
+func (d *Database) CountConversation(chatUserID string) (int, error) {
+	var count int
+	err := d.conn.QueryRow("SELECT COUNT(*) FROM chats WHERE chat_user_id = ? AND role <> 'system'", chatUserID).Scan(&count)
+
+	return count, err
+}
+
+func scanEntries(rows *sql.Rows) ([]Entry, error) {
 	defer rows.Close()
 
 	var chats []Entry
 	for rows.Next() {
 		var chat Entry
-		err := rows.Scan(&chat.ID, &chat.ChatUserID, &chat.Role, &chat.Text, &chat.Audio)
+		var createdAt sql.NullTime
+		err := rows.Scan(&chat.ID, &chat.ChatUserID, &chat.Role, &chat.Text, &chat.AudioKey, &chat.AudioFormat, &chat.AudioDuration, &chat.Score, &chat.Seq, &createdAt)
 		if err != nil {
 			return nil, err
 		}
+
+		if createdAt.Valid {
+			chat.CreatedAt = &createdAt.Time
+		}
 		chats = append(chats, chat)
 	}
 	return chats, nil
 }
+
+func (d *Database) GetChat(id string) (*Entry, error) {
+	var chat Entry
+	var audio sql.NullString
+	var createdAt sql.NullTime
+	err := d.conn.QueryRow("SELECT id, chat_user_id, role, text, audio, audio_key, audio_format, audio_duration, score, seq, created_at FROM chats WHERE id = ?", id).
+		Scan(&chat.ID, &chat.ChatUserID, &chat.Role, &chat.Text, &audio, &chat.AudioKey, &chat.AudioFormat, &chat.AudioDuration, &chat.Score, &chat.Seq, &createdAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	chat.Audio = audio.String
+	if createdAt.Valid {
+		chat.CreatedAt = &createdAt.Time
+	}
+
+	return &chat, nil
+}
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+// Concurrent answers to the same session must each get their own seq
+// instead of failing on the unique index.
+func TestCreateChatsConcurrentSeq(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+		migrate(t, db)
+
+		tx, err := db.BeginTx()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user, err := tx.CreateChatUser(ChatUser{Secret: "secret", Language: "en"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		const writers = 8
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+
+		for range writers {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				tx, err := db.BeginTx()
+				if err != nil {
+					errs <- err
+
+					return
+				}
+				defer tx.Rollback()
+
+				if _, err := tx.CreateChats(user.ID, []Entry{{Role: "user"}, {Role: "assistant"}}); err != nil {
+					errs <- err
+
+					return
+				}
+
+				errs <- tx.Commit()
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entries, err := db.GetChatsByChatUserID(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != writers*2 {
+			t.Fatalf("got %d entries, want %d", len(entries), writers*2)
+		}
+
+		for i, entry := range entries {
+			if entry.Seq != i+1 {
+				t.Fatalf("entry %d has seq %d, want %d", i, entry.Seq, i+1)
+			}
+		}
+	})
+}
+
+// Pages of the conversation skip the system prompt and count only what they
+// page through.
+func TestGetConversation(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+		migrate(t, db)
+
+		tx, err := db.BeginTx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		user, err := tx.CreateChatUser(ChatUser{Secret: "secret", Language: "en"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries := []Entry{{Role: "system"}}
+		for range 3 {
+			entries = append(entries, Entry{Role: "assistant"}, Entry{Role: "user"})
+		}
+
+		if _, err := tx.CreateChats(user.ID, entries); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		total, err := db.CountConversation(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if total != 6 {
+			t.Errorf("got %d entries counted, want 6", total)
+		}
+
+		tests := []struct {
+			offset, limit int
+			want          []int
+		}{
+			{0, 2, []int{2, 3}},
+			{4, 10, []int{6, 7}},
+			{6, 10, nil},
+		}
+
+		for _, test := range tests {
+			page, err := db.GetConversation(user.ID, test.offset, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var seqs []int
+			for _, entry := range page {
+				seqs = append(seqs, entry.Seq)
+			}
+
+			if !slices.Equal(seqs, test.want) {
+				t.Errorf("offset %d limit %d: got seqs %v, want %v", test.offset, test.limit, seqs, test.want)
+			}
+		}
+	})
+}
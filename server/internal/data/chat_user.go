@@ -2,31 +2,155 @@ package data
 
 import (
 	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/madeindra/mock-interview/server/internal/session"
 )
 
 type ChatUser struct {
 	ID       string `json:"id"`
 	Secret   string `json:"secret"`
 	Language string `json:"language"`
+	// Client is the API key hash or IP address the session was started from
+	Client  string   `json:"client"`
+	Persona string   `json:"persona"`
+	Role    string   `json:"role"`
+	Skills  []string `json:"skills"`
+	// Scoring enables rubric scoring of every answer, Mode decides when the
+	// candidate gets to see the scores
+	Scoring bool   `json:"scoring"`
+	Mode    string `json:"mode"`
+
+	Status    string     `json:"status"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+
+	// Duration and MaxQuestions limit the interview, zero is unlimited
+	Duration     time.Duration `json:"duration"`
+	MaxQuestions int           `json:"max_questions"`
+	// Paused is the time spent paused before PausedAt, the current pause
+	Paused   time.Duration `json:"paused"`
+	PausedAt *time.Time    `json:"paused_at"`
 }
 
-func (d *Database) CreateChatUser(tx *sql.Tx, secret, language string) (*ChatUser, error) {
-	id := uuid.New().String()
-	_, err := tx.Exec("INSERT INTO chat_users (id, secret, language) VALUES (?, ?, ?)", id, secret, language)
+// CreateChatUser stores a new active chat user with a generated ID.
+func (t *sqlTx) CreateChatUser(user ChatUser) (*ChatUser, error) {
+	skills, err := json.Marshal(user.Skills)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ID = uuid.New().String()
+	user.Status = session.STATUS_ACTIVE
+	user.StartedAt = time.Now().UTC().Truncate(time.Second)
+	_, err = t.conn.Exec("INSERT INTO chat_users (id, secret, language, client, persona, role, skills, scoring, mode, status, started_at, duration_seconds, max_questions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		user.ID, user.Secret, user.Language, user.Client, user.Persona, user.Role, string(skills), user.Scoring, user.Mode, user.Status, user.StartedAt.Format(timeLayout),
+		int(user.Duration.Seconds()), user.MaxQuestions)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ChatUser{ID: id, Secret: secret, Language: language}, nil
+	return &user, nil
 }
 
 func (d *Database) GetChatUser(id string) (*ChatUser, error) {
 	var user ChatUser
-	err := d.conn.QueryRow("SELECT id, secret, language FROM chat_users WHERE id = ?", id).Scan(&user.ID, &user.Secret, &user.Language)
+	var skills string
+	var startedAt, endedAt, pausedAt sql.NullTime
+	var duration, paused int
+	err := d.conn.QueryRow("SELECT id, secret, language, client, persona, role, skills, scoring, mode, status, started_at, ended_at, duration_seconds, max_questions, paused_seconds, paused_at FROM chat_users WHERE id = ?", id).
+		Scan(&user.ID, &user.Secret, &user.Language, &user.Client, &user.Persona, &user.Role, &skills, &user.Scoring, &user.Mode, &user.Status, &startedAt, &endedAt,
+			&duration, &user.MaxQuestions, &paused, &pausedAt)
 	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := json.Unmarshal([]byte(skills), &user.Skills); err != nil {
 		return nil, err
 	}
+
+	user.StartedAt = startedAt.Time
+	if endedAt.Valid {
+		user.EndedAt = &endedAt.Time
+	}
+
+	user.Duration = time.Duration(duration) * time.Second
+	user.Paused = time.Duration(paused) * time.Second
+	if pausedAt.Valid {
+		user.PausedAt = &pausedAt.Time
+	}
+
 	return &user, nil
 }
+
+// UpdateChatUserStatus moves the chat user to status if it is still in one
+// of the statuses it can come from, and reports whether it did. Ending
+// statuses also set the end time, and pauses are timed so they do not count
+// towards the interview's duration.
+func (t *sqlTx) UpdateChatUserStatus(id, status string) (bool, error) {
+	from := session.From(status)
+	if len(from) == 0 {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	query := "UPDATE chat_users SET status = ?"
+	values := []interface{}{status}
+
+	switch status {
+	case session.STATUS_PAUSED:
+		query += ", paused_at = ?"
+		values = append(values, now.Format(timeLayout))
+	case session.STATUS_ACTIVE:
+		var pausedAt sql.NullTime
+		if err := t.conn.QueryRow("SELECT paused_at FROM chat_users WHERE id = ?", id).Scan(&pausedAt); err != nil {
+			return false, err
+		}
+
+		var paused int
+		if pausedAt.Valid {
+			paused = int(now.Sub(pausedAt.Time).Seconds())
+		}
+
+		query += ", paused_seconds = paused_seconds + ?, paused_at = NULL"
+		values = append(values, paused)
+	case session.STATUS_ENDED, session.STATUS_EXPIRED:
+		query += ", ended_at = ?"
+		values = append(values, now.Format(timeLayout))
+	}
+
+	query += " WHERE id = ? AND status IN (?" + strings.Repeat(", ?", len(from)-1) + ")"
+	values = append(values, id)
+	for _, status := range from {
+		values = append(values, status)
+	}
+
+	result, err := t.conn.Exec(query, values...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// LockChatUser keeps other transactions from writing to the session until
+// this one ends, and returns its status as of then.
+func (t *sqlTx) LockChatUser(id string) (string, error) {
+	return lockChatUser(t.conn, id)
+}
+
+func lockChatUser(tx conn, id string) (string, error) {
+	var status string
+	err := tx.QueryRow("SELECT status FROM chat_users WHERE id = ?"+tx.dialect.lockRows, id).Scan(&status)
+
+	return status, err
+}
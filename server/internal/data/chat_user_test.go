@@ -0,0 +1,87 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/session"
+)
+
+// A status read with LockChatUser holds until the transaction ends.
+func TestLockChatUser(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+		migrate(t, db)
+
+		tx, err := db.BeginTx()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user, err := tx.CreateChatUser(ChatUser{Secret: "secret", Language: "en"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		locked, err := db.BeginTx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer locked.Rollback()
+
+		status, err := locked.LockChatUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status != session.STATUS_ACTIVE {
+			t.Fatalf("got status %q, want %q", status, session.STATUS_ACTIVE)
+		}
+
+		paused := make(chan error, 1)
+		go func() {
+			tx, err := db.BeginTx()
+			if err != nil {
+				paused <- err
+
+				return
+			}
+			defer tx.Rollback()
+
+			if _, err := tx.UpdateChatUserStatus(user.ID, session.STATUS_PAUSED); err != nil {
+				paused <- err
+
+				return
+			}
+
+			paused <- tx.Commit()
+		}()
+
+		select {
+		case err := <-paused:
+			t.Fatalf("the session was paused while locked: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		if err := locked.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := <-paused; err != nil {
+			t.Fatal(err)
+		}
+
+		current, err := db.GetChatUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if current.Status != session.STATUS_PAUSED {
+			t.Errorf("got status %q once unlocked, want %q", current.Status, session.STATUS_PAUSED)
+		}
+	})
+}
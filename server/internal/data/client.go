@@ -2,72 +2,82 @@ package data
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 
+	_ "github.com/lib/pq"
 	_ "modernc.org/sqlite"
 )
 
+// Database is the SQL store, on SQLite or PostgreSQL.
 type Database struct {
-	conn *sql.DB
+	db   *sql.DB
+	conn conn
 }
 
-func New(dbPath string) *Database {
-	var err error
-	db, err := sql.Open("sqlite", dbPath)
+// New opens the database and applies the pending migrations.
+func New(dsn string) *Database {
+	d, err := Open(dsn)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	migrate(db)
+	migrated, err := d.Migrate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, migration := range migrated {
+		log.Printf("applied migration %d %s", migration.Version, migration.Name)
+	}
 
-	return &Database{conn: db}
+	return d
 }
 
-func migrate(db *sql.DB) {
-	chatUserTable := `CREATE TABLE IF NOT EXISTS chat_users (
-		id VARCHAR PRIMARY KEY,
-		secret VARCHAR NOT NULL,
-		language VARCHAR DEFAULT 'en'
-	);`
-
-	chatTable := `CREATE TABLE IF NOT EXISTS chats (
-		id VARCHAR PRIMARY KEY,
-		chat_user_id VARCHAR,
-		role VARCHAR,
-		text VARCHAR,
-		audio VARCHAR,
-		FOREIGN KEY(chat_user_id) REFERENCES chat_users(id)
-	);`
-
-	tx, err := db.Begin()
+// Open opens the database as it is, without migrating it. A postgres:// URL
+// opens PostgreSQL, anything else is the path of a SQLite file.
+func Open(dsn string) (*Database, error) {
+	dialect := dialectOf(dsn)
+
+	db, err := sql.Open(dialect.driver, dialect.dataSource(dsn))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	_, err = tx.Exec(chatUserTable)
-	if err != nil {
-		log.Fatal(err)
+	return &Database{db: db, conn: conn{db: db, dialect: dialect}}, nil
+}
+
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// notFound replaces the driver's missing row error with ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
 	}
 
-	_, err = tx.Exec(chatTable)
+	return err
+}
+
+func (d *Database) BeginTx() (Tx, error) {
+	tx, err := d.db.Begin()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		log.Fatal(err)
-	}
+	return &sqlTx{tx: tx, conn: conn{db: tx, dialect: d.conn.dialect}}, nil
 }
 
-func (d *Database) BeginTx() (*sql.Tx, error) {
-	return d.conn.Begin()
+type sqlTx struct {
+	tx   *sql.Tx
+	conn conn
 }
 
-func (d *Database) RollbackTx(tx *sql.Tx) error {
-	return tx.Rollback()
+func (t *sqlTx) Commit() error {
+	return t.tx.Commit()
 }
 
-func (d *Database) CommitTx(tx *sql.Tx) error {
-	return tx.Commit()
+func (t *sqlTx) Rollback() error {
+	return t.tx.Rollback()
 }
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// dialect holds what differs between the SQL databases the store runs on.
+type dialect struct {
+	driver string
+	// migrations is the directory of the dialect's migrations
+	migrations string
+	// numbered placeholders replace the ? the queries are written with
+	numbered bool
+	// params are appended to the data source name
+	params string
+	// lockRows is appended to a select to lock the rows it reads until the
+	// transaction ends. Empty when beginning a transaction already locks the
+	// database.
+	lockRows string
+	// lockMigrationsQuery makes other migrators wait until the transaction
+	// ends, empty when beginning a transaction already locks the database
+	lockMigrationsQuery string
+
+	schemaMigrationsTable string
+}
+
+var (
+	sqliteDialect = &dialect{
+		driver:     "sqlite",
+		migrations: "migrations/sqlite",
+		// transactions take the write lock when they begin, and wait for it
+		// instead of failing with SQLITE_BUSY
+		params: "_pragma=busy_timeout(5000)&_txlock=immediate",
+
+		schemaMigrationsTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR NOT NULL,
+			applied_at DATETIME NOT NULL
+		);`,
+	}
+
+	postgresDialect = &dialect{
+		driver:     "postgres",
+		migrations: "migrations/postgres",
+		numbered:   true,
+
+		lockRows:            " FOR UPDATE",
+		lockMigrationsQuery: "SELECT pg_advisory_xact_lock(?)",
+
+		schemaMigrationsTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		);`,
+	}
+)
+
+// dialectOf picks PostgreSQL for postgres:// URLs and SQLite for anything
+// else, which is a file path.
+func dialectOf(dsn string) *dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return postgresDialect
+	}
+
+	return sqliteDialect
+}
+
+// dataSource appends the dialect's params to dsn.
+func (d *dialect) dataSource(dsn string) string {
+	if d.params == "" {
+		return dsn
+	}
+
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + d.params
+	}
+
+	return dsn + "?" + d.params
+}
+
+// rebind turns the ? placeholders outside of string literals into $1, $2...
+// for dialects that number them.
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	var n int
+	var quoted bool
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// conn runs queries written with ? placeholders on the database or in a
+// transaction.
+type conn struct {
+	db interface {
+		Exec(query string, args ...any) (sql.Result, error)
+		Query(query string, args ...any) (*sql.Rows, error)
+		QueryRow(query string, args ...any) *sql.Row
+	}
+	dialect *dialect
+}
+
+func (c conn) Exec(query string, args ...any) (sql.Result, error) {
+	return c.db.Exec(c.dialect.rebind(query), args...)
+}
+
+func (c conn) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.db.Query(c.dialect.rebind(query), args...)
+}
+
+func (c conn) QueryRow(query string, args ...any) *sql.Row {
+	return c.db.QueryRow(c.dialect.rebind(query), args...)
+}
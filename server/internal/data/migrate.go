@@ -0,0 +1,312 @@
+package data
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// ErrUnknownSchema is returned for a database migrated by a newer build.
+var ErrUnknownSchema = errors.New("database schema is newer than this build")
+
+// ErrBaseline is returned for a rollback past the baseline, which would drop
+// every table with its data.
+var ErrBaseline = errors.New("the baseline migration cannot be rolled back")
+
+const (
+	baselineVersion = 1
+
+	// migrationLockKey names the advisory lock migrators take on PostgreSQL
+	migrationLockKey = 0x6d69677261746531
+)
+
+type Migration struct {
+	Version int
+	Name    string
+
+	up   string
+	down string
+}
+
+// MigrationState is a migration with when it was applied, nil while it is
+// pending. Unknown migrations were applied by a newer build.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// Migrations returns the migrations of the database embedded in the build,
+// in order.
+func (d *Database) Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, d.conn.dialect.migrations+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		// e.g. 0001_baseline.up.sql
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		stem, direction, _ := strings.Cut(base, ".")
+		number, name, _ := strings.Cut(stem, "_")
+
+		version, err := strconv.Atoi(number)
+		if err != nil || version < 1 || name == "" {
+			return nil, fmt.Errorf("invalid migration name: %s", file)
+		}
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, name)
+		}
+
+		switch direction {
+		case "up":
+			migration.up = string(content)
+		case "down":
+			migration.down = string(content)
+		default:
+			return nil, fmt.Errorf("invalid migration direction: %s", file)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %d needs an up", migration.Version)
+		}
+
+		// the baseline is never rolled back, every later migration must be
+		if migration.down == "" && migration.Version != baselineVersion {
+			return nil, fmt.Errorf("migration %d needs a down", migration.Version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	return migrations, nil
+}
+
+// Migrate applies the pending migrations in order and returns them. They
+// are applied in a single transaction, so either all or none are, while
+// other migrators wait. A database migrated by a newer build is refused.
+func (d *Database) Migrate() ([]Migration, error) {
+	known, err := d.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []Migration
+	err = d.inMigrationTx(func(tx conn) error {
+		applied, err := appliedMigrations(tx, known)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range known {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if _, err := tx.Exec(migration.up); err != nil {
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().UTC().Format(timeLayout))
+			if err != nil {
+				return err
+			}
+
+			migrated = append(migrated, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrated, nil
+}
+
+// Rollback reverts the latest steps applied migrations in a single
+// transaction and returns them. Nothing is reverted when that would include
+// the baseline.
+func (d *Database) Rollback(steps int) ([]Migration, error) {
+	known, err := d.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	err = d.inMigrationTx(func(tx conn) error {
+		applied, err := appliedMigrations(tx, known)
+		if err != nil {
+			return err
+		}
+
+		for i := len(known) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := known[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Version == baselineVersion {
+				return ErrBaseline
+			}
+
+			if _, err := tx.Exec(migration.down); err != nil {
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+
+			if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version); err != nil {
+				return err
+			}
+
+			rolledBack = append(rolledBack, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rolledBack, nil
+}
+
+// MigrationStatus lists the known migrations and those applied by a newer
+// build, in order.
+func (d *Database) MigrationStatus() ([]MigrationState, error) {
+	known, err := d.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.conn.Exec(d.conn.dialect.schemaMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	applied, err := schemaMigrations(d.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, migration := range known {
+		state := MigrationState{Version: migration.Version, Name: migration.Name}
+		if appliedState, ok := applied[migration.Version]; ok {
+			state.AppliedAt = appliedState.AppliedAt
+			delete(applied, migration.Version)
+		}
+
+		states = append(states, state)
+	}
+
+	for _, state := range applied {
+		state.Unknown = true
+		states = append(states, state)
+	}
+
+	slices.SortFunc(states, func(a, b MigrationState) int {
+		return a.Version - b.Version
+	})
+
+	return states, nil
+}
+
+// appliedMigrations returns the applied versions and refuses any unknown
+// to this build.
+func appliedMigrations(tx conn, known []Migration) (map[int]MigrationState, error) {
+	if _, err := tx.Exec(tx.dialect.schemaMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	applied, err := schemaMigrations(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := 0
+	if len(known) > 0 {
+		latest = known[len(known)-1].Version
+	}
+
+	for version := range applied {
+		if !slices.ContainsFunc(known, func(migration Migration) bool { return migration.Version == version }) {
+			return nil, fmt.Errorf("%w: version %d is applied, the latest known is %d", ErrUnknownSchema, version, latest)
+		}
+	}
+
+	return applied, nil
+}
+
+func schemaMigrations(q conn) (map[int]MigrationState, error) {
+	rows, err := q.Query("SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]MigrationState{}
+	for rows.Next() {
+		var state MigrationState
+		var appliedAt time.Time
+		if err := rows.Scan(&state.Version, &state.Name, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		state.AppliedAt = &appliedAt
+		applied[state.Version] = state
+	}
+
+	return applied, rows.Err()
+}
+
+// inMigrationTx runs fn in a transaction no other migrator runs alongside.
+func (d *Database) inMigrationTx(fn func(tx conn) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c := conn{db: tx, dialect: d.conn.dialect}
+	if query := c.dialect.lockMigrationsQuery; query != "" {
+		if _, err := c.Exec(query, migrationLockKey); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(c); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
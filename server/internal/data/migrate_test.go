@@ -0,0 +1,290 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// legacySchema is what the server created before versioned migrations.
+const legacySchema = `CREATE TABLE IF NOT EXISTS chat_users (
+	id VARCHAR PRIMARY KEY,
+	secret VARCHAR NOT NULL,
+	language VARCHAR DEFAULT 'en'
+);
+
+CREATE TABLE IF NOT EXISTS chats (
+	id VARCHAR PRIMARY KEY,
+	chat_user_id VARCHAR,
+	role VARCHAR,
+	text VARCHAR,
+	audio VARCHAR,
+	FOREIGN KEY(chat_user_id) REFERENCES chat_users(id)
+);`
+
+func migrate(t *testing.T, db *Database) []Migration {
+	t.Helper()
+
+	migrated, err := db.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return migrated
+}
+
+func TestMigrateUpDownStatus(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+
+		known, err := db.Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(known) < 2 || known[0].Version != baselineVersion {
+			t.Fatalf("got migrations %+v, want the baseline and more", known)
+		}
+
+		if migrated := migrate(t, db); len(migrated) != len(known) {
+			t.Fatalf("got %d migrations applied to a new database, want %d", len(migrated), len(known))
+		}
+
+		if migrated := migrate(t, db); len(migrated) != 0 {
+			t.Errorf("got %d migrations applied twice, want none", len(migrated))
+		}
+
+		rolledBack, err := db.Rollback(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rolledBack) != 2 || rolledBack[0].Version != known[len(known)-1].Version {
+			t.Fatalf("got %+v rolled back, want the latest two", rolledBack)
+		}
+
+		states, err := db.MigrationStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, state := range states {
+			if pending := i >= len(known)-2; (state.AppliedAt == nil) != pending {
+				t.Errorf("migration %d: got applied at %v, want pending %v", state.Version, state.AppliedAt, pending)
+			}
+		}
+
+		// every down leaves a schema the up applies to again
+		if _, err := db.Rollback(len(known) - 3); err != nil {
+			t.Fatal(err)
+		}
+
+		if migrated := migrate(t, db); len(migrated) != len(known)-1 {
+			t.Errorf("got %d migrations applied again, want %d", len(migrated), len(known)-1)
+		}
+	})
+}
+
+func TestRollbackRefusesBaseline(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+		known := migrate(t, db)
+
+		if _, err := db.Rollback(len(known)); !errors.Is(err, ErrBaseline) {
+			t.Fatalf("got %v rolling back every migration, want ErrBaseline", err)
+		}
+
+		// nothing is reverted when the baseline is part of the rollback
+		states, err := db.MigrationStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, state := range states {
+			if state.AppliedAt == nil {
+				t.Errorf("migration %d was rolled back", state.Version)
+			}
+		}
+	})
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+		migrate(t, db)
+
+		if _, err := db.conn.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', '2030-01-01 00:00:00')"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Migrate(); !errors.Is(err, ErrUnknownSchema) {
+			t.Errorf("got %v, want ErrUnknownSchema", err)
+		}
+
+		states, err := db.MigrationStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if last := states[len(states)-1]; last.Version != 9999 || !last.Unknown {
+			t.Errorf("got last state %+v, want the unknown version 9999", last)
+		}
+	})
+}
+
+// A database created before versioned migrations keeps its sessions and
+// gets every migration applied on top.
+func TestMigrateLegacyDatabase(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		db := openTestDatabase(t, dsn)
+
+		if _, err := db.conn.Exec(legacySchema); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, query := range []string{
+			"INSERT INTO chat_users (id, secret, language) VALUES ('first', 'secret', 'en'), ('second', 'secret', 'id')",
+			"INSERT INTO chats (id, chat_user_id, role, text, audio) VALUES ('c', 'first', 'system', 'prompt', ''), ('a', 'first', 'assistant', 'hello', ''), ('b', 'second', 'assistant', 'halo', '')",
+		} {
+			if _, err := db.conn.Exec(query); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		known, err := db.Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if migrated := migrate(t, db); len(migrated) != len(known) {
+			t.Fatalf("got %d migrations applied to a legacy database, want %d", len(migrated), len(known))
+		}
+
+		user, err := db.GetChatUser("second")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if user.Language != "id" || user.Status != "active" || !user.StartedAt.IsZero() {
+			t.Errorf("got session %+v, want the legacy one active without a start", user)
+		}
+
+		entries, err := db.GetChatsByChatUserID("first")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// entries are numbered in the order they were inserted
+		if len(entries) != 2 || entries[0].ID != "c" || entries[0].Seq != 1 || entries[1].ID != "a" || entries[1].Seq != 2 {
+			t.Errorf("got entries %+v, want c then a numbered from 1", entries)
+		}
+	})
+}
+
+// Replicas starting together must not both apply the same migration.
+func TestMigrateConcurrently(t *testing.T) {
+	eachDialect(t, func(t *testing.T, dsn string) {
+		const migrators = 8
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		applied := make(chan int, migrators)
+		errs := make(chan error, migrators)
+
+		for range migrators {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				db, err := Open(dsn)
+				if err != nil {
+					errs <- err
+
+					return
+				}
+				defer db.Close()
+
+				// open the connection before racing the others
+				if err := db.db.Ping(); err != nil {
+					errs <- err
+
+					return
+				}
+				<-start
+
+				migrated, err := db.Migrate()
+				applied <- len(migrated)
+				errs <- err
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+		close(applied)
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		total := 0
+		for count := range applied {
+			total += count
+		}
+
+		db, err := Open(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		known, err := db.Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if total != len(known) {
+			t.Errorf("got %d migrations applied, want each of the %d applied once", total, len(known))
+		}
+	})
+}
+
+// The PostgreSQL migrations are checked without a server: they must mirror
+// the SQLite ones and use none of SQLite's own syntax.
+func TestPostgresMigrations(t *testing.T) {
+	sqlite, err := (&Database{conn: conn{dialect: sqliteDialect}}).Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postgres, err := (&Database{conn: conn{dialect: postgresDialect}}).Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("got %d PostgreSQL migrations, want one per SQLite migration (%d)", len(postgres), len(sqlite))
+	}
+
+	for i, migration := range postgres {
+		if migration.Version != sqlite[i].Version || migration.Name != sqlite[i].Name {
+			t.Errorf("got migration %d %s, want %d %s", migration.Version, migration.Name, sqlite[i].Version, sqlite[i].Name)
+		}
+
+		for _, token := range []string{"DATETIME", "rowid", " REAL ", "PRAGMA", "AUTOINCREMENT"} {
+			if strings.Contains(migration.up, token) || strings.Contains(migration.down, token) {
+				t.Errorf("migration %d %s uses %q, which PostgreSQL does not know", migration.Version, migration.Name, strings.TrimSpace(token))
+			}
+		}
+
+		for _, line := range strings.Split(migration.up, "\n") {
+			if strings.Contains(line, "BOOLEAN") && strings.Contains(line, "DEFAULT 0") {
+				t.Errorf("migration %d %s defaults a boolean to 0", migration.Version, migration.Name)
+			}
+		}
+	}
+}
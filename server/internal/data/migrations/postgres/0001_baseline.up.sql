@@ -0,0 +1,16 @@
+-- the schema of the first release, which databases created before versioned
+-- migrations already have
+CREATE TABLE IF NOT EXISTS chat_users (
+	id VARCHAR PRIMARY KEY,
+	secret VARCHAR NOT NULL,
+	language VARCHAR DEFAULT 'en'
+);
+
+CREATE TABLE IF NOT EXISTS chats (
+	id VARCHAR PRIMARY KEY,
+	chat_user_id VARCHAR,
+	role VARCHAR,
+	text VARCHAR,
+	audio VARCHAR,
+	FOREIGN KEY(chat_user_id) REFERENCES chat_users(id)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE usages (
+	id VARCHAR PRIMARY KEY,
+	chat_user_id VARCHAR,
+	kind VARCHAR NOT NULL,
+	provider VARCHAR NOT NULL,
+	model VARCHAR NOT NULL,
+	input_tokens INTEGER NOT NULL DEFAULT 0,
+	output_tokens INTEGER NOT NULL DEFAULT 0,
+	audio_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+	characters INTEGER NOT NULL DEFAULT 0,
+	cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY(chat_user_id) REFERENCES chat_users(id)
+);
+
+CREATE INDEX usages_chat_user_id ON usages (chat_user_id);
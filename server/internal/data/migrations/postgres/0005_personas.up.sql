@@ -0,0 +1 @@
+ALTER TABLE chat_users ADD COLUMN persona VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE chat_users DROP COLUMN ended_at;
+ALTER TABLE chat_users DROP COLUMN started_at;
+ALTER TABLE chat_users DROP COLUMN status;
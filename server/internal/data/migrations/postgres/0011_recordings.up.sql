@@ -0,0 +1,2 @@
+ALTER TABLE chats ADD COLUMN audio_format VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN audio_duration DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+ALTER TABLE chats ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+
+-- entries from before are numbered in the order they were stored
+UPDATE chats SET seq = numbered.seq
+FROM (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY chat_user_id ORDER BY created_at, ctid) AS seq FROM chats
+) AS numbered
+WHERE chats.id = numbered.id;
+
+CREATE UNIQUE INDEX chats_chat_user_id_seq ON chats (chat_user_id, seq);
@@ -0,0 +1,6 @@
+CREATE TABLE summaries (
+	chat_user_id VARCHAR PRIMARY KEY,
+	text VARCHAR NOT NULL,
+	entry_count INTEGER NOT NULL,
+	FOREIGN KEY(chat_user_id) REFERENCES chat_users(id)
+);
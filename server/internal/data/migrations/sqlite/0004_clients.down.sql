@@ -0,0 +1,4 @@
+DROP INDEX usages_client_created_at;
+
+ALTER TABLE usages DROP COLUMN client;
+ALTER TABLE chat_users DROP COLUMN client;
@@ -0,0 +1,4 @@
+ALTER TABLE chat_users ADD COLUMN client VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE usages ADD COLUMN client VARCHAR NOT NULL DEFAULT '';
+
+CREATE INDEX usages_client_created_at ON usages (client, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE chat_users DROP COLUMN skills;
+ALTER TABLE chat_users DROP COLUMN role;
+
+DROP TABLE reports;
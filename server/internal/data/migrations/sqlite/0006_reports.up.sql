@@ -0,0 +1,10 @@
+CREATE TABLE reports (
+	chat_user_id VARCHAR PRIMARY KEY,
+	content VARCHAR NOT NULL,
+	entry_count INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY(chat_user_id) REFERENCES chat_users(id)
+);
+
+ALTER TABLE chat_users ADD COLUMN role VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE chat_users ADD COLUMN skills VARCHAR NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+ALTER TABLE chats DROP COLUMN score;
+ALTER TABLE chat_users DROP COLUMN mode;
+ALTER TABLE chat_users DROP COLUMN scoring;
@@ -0,0 +1,3 @@
+ALTER TABLE chat_users ADD COLUMN scoring BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE chat_users ADD COLUMN mode VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN score VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- sessions from before have no start and never expire
+ALTER TABLE chat_users ADD COLUMN status VARCHAR NOT NULL DEFAULT 'active';
+ALTER TABLE chat_users ADD COLUMN started_at DATETIME;
+ALTER TABLE chat_users ADD COLUMN ended_at DATETIME;
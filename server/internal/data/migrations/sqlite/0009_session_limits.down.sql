@@ -0,0 +1,4 @@
+ALTER TABLE chat_users DROP COLUMN paused_at;
+ALTER TABLE chat_users DROP COLUMN paused_seconds;
+ALTER TABLE chat_users DROP COLUMN max_questions;
+ALTER TABLE chat_users DROP COLUMN duration_seconds;
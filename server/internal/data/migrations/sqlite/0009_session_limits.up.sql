@@ -0,0 +1,4 @@
+ALTER TABLE chat_users ADD COLUMN duration_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chat_users ADD COLUMN max_questions INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chat_users ADD COLUMN paused_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chat_users ADD COLUMN paused_at DATETIME;
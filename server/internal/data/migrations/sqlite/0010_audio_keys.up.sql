@@ -0,0 +1 @@
+ALTER TABLE chats ADD COLUMN audio_key VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE chats DROP COLUMN audio_duration;
+ALTER TABLE chats DROP COLUMN audio_format;
@@ -0,0 +1,2 @@
+-- entries from before have no timestamp
+ALTER TABLE chats ADD COLUMN created_at DATETIME;
@@ -0,0 +1,3 @@
+DROP INDEX chats_chat_user_id_seq;
+
+ALTER TABLE chats DROP COLUMN seq;
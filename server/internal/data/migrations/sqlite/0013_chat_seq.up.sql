@@ -0,0 +1,8 @@
+ALTER TABLE chats ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+
+-- entries from before are numbered in the order they were inserted
+UPDATE chats SET seq = (
+	SELECT COUNT(*) FROM chats AS earlier WHERE earlier.chat_user_id = chats.chat_user_id AND earlier.rowid <= chats.rowid
+);
+
+CREATE UNIQUE INDEX chats_chat_user_id_seq ON chats (chat_user_id, seq);
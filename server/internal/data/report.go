@@ -0,0 +1,37 @@
+package data
+
+import (
+	"time"
+)
+
+// Report is the evaluation of a chat as JSON, made when the chat had
+// EntryCount entries.
+type Report struct {
+	ChatUserID string    `json:"chat_user_id"`
+	Content    string    `json:"content"`
+	EntryCount int       `json:"entry_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (d *Database) GetReport(chatUserID string) (*Report, error) {
+	var report Report
+	err := d.conn.QueryRow("SELECT chat_user_id, content, entry_count, created_at FROM reports WHERE chat_user_id = ?", chatUserID).
+		Scan(&report.ChatUserID, &report.Content, &report.EntryCount, &report.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &report, nil
+}
+
+func (d *Database) SaveReport(chatUserID, content string, entryCount int) (*Report, error) {
+	now := time.Now().UTC()
+	_, err := d.conn.Exec(`INSERT INTO reports (chat_user_id, content, entry_count, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(chat_user_id) DO UPDATE SET content = excluded.content, entry_count = excluded.entry_count, created_at = excluded.created_at`,
+		chatUserID, content, entryCount, now.Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{ChatUserID: chatUserID, Content: content, EntryCount: entryCount, CreatedAt: now.Truncate(time.Second)}, nil
+}
@@ -0,0 +1,47 @@
+package data
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Store keeps the sessions, their entries and what is derived from them.
+type Store interface {
+	// BeginTx starts a transaction for writes that must happen together
+	BeginTx() (Tx, error)
+
+	GetChatUser(id string) (*ChatUser, error)
+	GetChatsByChatUserID(chatUserID string) ([]Entry, error)
+	GetConversation(chatUserID string, offset, limit int) ([]Entry, error)
+	CountConversation(chatUserID string) (int, error)
+	GetChat(id string) (*Entry, error)
+
+	GetSummary(chatUserID string) (*Summary, error)
+	SaveSummary(chatUserID, text string, entryCount int) (*Summary, error)
+
+	GetReport(chatUserID string) (*Report, error)
+	SaveReport(chatUserID, content string, entryCount int) (*Report, error)
+
+	CreateUsages(usages []Usage) error
+	GetUsageTotalsByChatUserID(chatUserID string) ([]UsageTotal, error)
+	GetUsageTotals(from, to time.Time) ([]UsageTotal, error)
+	GetUsageTotalsByChatUser(from, to time.Time, limit int) ([]UsageTotal, error)
+	GetSpendByClient(client string, since time.Time) (float64, error)
+
+	Close() error
+}
+
+// Tx writes sessions and their entries, nothing is kept unless it is
+// committed.
+type Tx interface {
+	CreateChatUser(user ChatUser) (*ChatUser, error)
+	UpdateChatUserStatus(id, status string) (bool, error)
+	LockChatUser(id string) (string, error)
+	CreateChats(chatUserID string, chats []Entry) ([]Entry, error)
+
+	Commit() error
+	Rollback() error
+}
@@ -0,0 +1,31 @@
+package data
+
+// Summary condenses the first EntryCount conversation entries of a chat,
+// counted after the system prompt, so they do not have to be sent again.
+type Summary struct {
+	ChatUserID string `json:"chat_user_id"`
+	Text       string `json:"text"`
+	EntryCount int    `json:"entry_count"`
+}
+
+func (d *Database) GetSummary(chatUserID string) (*Summary, error) {
+	var summary Summary
+	err := d.conn.QueryRow("SELECT chat_user_id, text, entry_count FROM summaries WHERE chat_user_id = ?", chatUserID).
+		Scan(&summary.ChatUserID, &summary.Text, &summary.EntryCount)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &summary, nil
+}
+
+func (d *Database) SaveSummary(chatUserID, text string, entryCount int) (*Summary, error) {
+	_, err := d.conn.Exec(`INSERT INTO summaries (chat_user_id, text, entry_count) VALUES (?, ?, ?)
+		ON CONFLICT(chat_user_id) DO UPDATE SET text = excluded.text, entry_count = excluded.entry_count`,
+		chatUserID, text, entryCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Summary{ChatUserID: chatUserID, Text: text, EntryCount: entryCount}, nil
+}
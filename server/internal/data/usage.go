@@ -0,0 +1,151 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Usage struct {
+	ID           string    `json:"id"`
+	ChatUserID   string    `json:"chat_user_id"`
+	Kind         string    `json:"kind"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	AudioSeconds float64   `json:"audio_seconds"`
+	Characters   int       `json:"characters"`
+	Cost         float64   `json:"cost"`
+	Client       string    `json:"client"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UsageTotal sums usages sharing the same kind, provider and model, or
+// the usages of one chat user when ChatUserID is set.
+type UsageTotal struct {
+	ChatUserID   string  `json:"chat_user_id,omitempty"`
+	Kind         string  `json:"kind,omitempty"`
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	AudioSeconds float64 `json:"audio_seconds"`
+	Characters   int     `json:"characters"`
+	Cost         float64 `json:"cost"`
+}
+
+const timeLayout = "2006-01-02 15:04:05"
+
+const usageTotalColumns = "COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(audio_seconds), 0), COALESCE(SUM(characters), 0), COALESCE(SUM(cost), 0)"
+
+// CreateUsages stores usages, those without a chat user are kept
+// unattributed so that failed session starts still show up in totals.
+func (d *Database) CreateUsages(usages []Usage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+
+	query := "INSERT INTO usages (id, chat_user_id, kind, provider, model, input_tokens, output_tokens, audio_seconds, characters, cost, client, created_at) VALUES "
+	var values []interface{}
+	placeholders := make([]string, len(usages))
+
+	now := time.Now().UTC().Format(timeLayout)
+	for i, usage := range usages {
+		var chatUserID sql.NullString
+		if usage.ChatUserID != "" {
+			chatUserID = sql.NullString{String: usage.ChatUserID, Valid: true}
+		}
+
+		placeholders[i] = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+		values = append(values, uuid.New().String(), chatUserID, usage.Kind, usage.Provider, usage.Model,
+			usage.InputTokens, usage.OutputTokens, usage.AudioSeconds, usage.Characters, usage.Cost, usage.Client, now)
+	}
+
+	query += strings.Join(placeholders, ",")
+
+	_, err := d.conn.Exec(query, values...)
+	return err
+}
+
+func (d *Database) GetUsageTotalsByChatUserID(chatUserID string) ([]UsageTotal, error) {
+	rows, err := d.conn.Query("SELECT kind, provider, model, "+usageTotalColumns+
+		" FROM usages WHERE chat_user_id = ? GROUP BY kind, provider, model ORDER BY kind, provider, model", chatUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var total UsageTotal
+		err := rows.Scan(&total.Kind, &total.Provider, &total.Model, &total.Calls, &total.InputTokens,
+			&total.OutputTokens, &total.AudioSeconds, &total.Characters, &total.Cost)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetUsageTotals sums usages created in [from, to] by kind, provider and model.
+func (d *Database) GetUsageTotals(from, to time.Time) ([]UsageTotal, error) {
+	rows, err := d.conn.Query("SELECT kind, provider, model, "+usageTotalColumns+
+		" FROM usages WHERE created_at >= ? AND created_at <= ? GROUP BY kind, provider, model ORDER BY kind, provider, model",
+		from.UTC().Format(timeLayout), to.UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var total UsageTotal
+		err := rows.Scan(&total.Kind, &total.Provider, &total.Model, &total.Calls, &total.InputTokens,
+			&total.OutputTokens, &total.AudioSeconds, &total.Characters, &total.Cost)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetUsageTotalsByChatUser sums usages created in [from, to] per chat user,
+// most expensive first.
+func (d *Database) GetUsageTotalsByChatUser(from, to time.Time, limit int) ([]UsageTotal, error) {
+	rows, err := d.conn.Query("SELECT COALESCE(chat_user_id, ''), "+usageTotalColumns+
+		" FROM usages WHERE created_at >= ? AND created_at <= ? GROUP BY chat_user_id ORDER BY SUM(cost) DESC LIMIT ?",
+		from.UTC().Format(timeLayout), to.UTC().Format(timeLayout), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var total UsageTotal
+		err := rows.Scan(&total.ChatUserID, &total.Calls, &total.InputTokens,
+			&total.OutputTokens, &total.AudioSeconds, &total.Characters, &total.Cost)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetSpendByClient sums the cost of usages attributed to a client since the
+// given time.
+func (d *Database) GetSpendByClient(client string, since time.Time) (float64, error) {
+	var spend float64
+	err := d.conn.QueryRow("SELECT COALESCE(SUM(cost), 0) FROM usages WHERE client = ? AND created_at >= ?",
+		client, since.UTC().Format(timeLayout)).Scan(&spend)
+
+	return spend, err
+}
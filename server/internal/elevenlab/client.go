@@ -9,10 +9,23 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+	"github.com/madeindra/mock-interview/server/internal/usage"
 )
 
 type Client interface {
-	TextToSpeech(string) (io.ReadCloser, error)
+	TextToSpeech(context.Context, string, SpeechOptions) (io.ReadCloser, error)
+}
+
+// SpeechOptions override the client's voice, model and output format for a
+// single call, empty fields keep the defaults.
+type SpeechOptions struct {
+	Voice  string
+	Model  string
+	Format string
 }
 
 type ElevenLab struct {
@@ -20,12 +33,31 @@ type ElevenLab struct {
 	baseURL  string
 	ttsModel string
 	ttsVoice string
+
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+type Option func(*ElevenLab)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *ElevenLab) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout bounds every call, including reading the audio.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *ElevenLab) {
+		c.timeout = timeout
+	}
 }
 
 const (
-	baseURL  = "https://api.elevenlabs.io/v1"
-	ttsModel = "eleven_multilingual_v2"
-	ttsVoice = "cgSgspJ2msm6clMCkdW9"
+	providerName = "elevenlab"
+	baseURL      = "https://api.elevenlabs.io/v1"
+	ttsModel     = "eleven_multilingual_v2"
+	ttsVoice     = "cgSgspJ2msm6clMCkdW9"
 )
 
 var defaultVoiceSetting = VoiceSetting{
@@ -33,50 +65,99 @@ var defaultVoiceSetting = VoiceSetting{
 	SimilarityBoost: 0.75,
 }
 
-func NewElevenLab(apiKey string) *ElevenLab {
-	return &ElevenLab{
-		apiKey:   apiKey,
-		baseURL:  baseURL,
-		ttsModel: ttsModel,
-		ttsVoice: ttsVoice,
+func NewElevenLab(apiKey string, opts ...Option) *ElevenLab {
+	client := &ElevenLab{
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		ttsModel:   ttsModel,
+		ttsVoice:   ttsVoice,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(client)
 	}
+
+	return client
 }
 
-func (c *ElevenLab) TextToSpeech(input string) (io.ReadCloser, error) {
-	url, err := url.JoinPath(c.baseURL, "text-to-speech", c.ttsVoice)
+func (c *ElevenLab) TextToSpeech(ctx context.Context, input string, opts SpeechOptions) (io.ReadCloser, error) {
+	voice := c.ttsVoice
+	if opts.Voice != "" {
+		voice = opts.Voice
+	}
+
+	endpoint, err := url.JoinPath(c.baseURL, "text-to-speech", voice)
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.Format != "" {
+		endpoint += "?" + url.Values{"output_format": {opts.Format}}.Encode()
+	}
+
 	ttsReq := TTSRequest{
 		Text:         input,
 		ModelID:      c.ttsModel,
 		VoiceSetting: defaultVoiceSetting,
 	}
+	if opts.Model != "" {
+		ttsReq.ModelID = opts.Model
+	}
 
 	body, err := json.Marshal(ttsReq)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewBuffer(body))
+	ctx, cancel := c.withTimeout(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(body))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	req.Header.Set("xi-api-key", c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	respBody, err := getResponseBody(resp)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return respBody, nil
+	usage.Add(ctx, usage.Record{
+		Kind:       usage.KIND_SPEECH,
+		Provider:   providerName,
+		Model:      ttsReq.ModelID,
+		Characters: utf8.RuneCountInString(input),
+	})
+
+	return &cancelOnClose{ReadCloser: respBody, cancel: cancel}, nil
+}
+
+func (c *ElevenLab) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
 }
 
 func getResponseBody(resp *http.Response) (io.ReadCloser, error) {
@@ -85,8 +166,22 @@ func getResponseBody(resp *http.Response) (io.ReadCloser, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, apierror.FromResponse(providerName, resp, parseError)
 	}
 
 	return resp.Body, nil
 }
+
+// parseError reads the detail object, validation errors come back with a
+// list instead and are left unclassified.
+func parseError(body []byte) apierror.Detail {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return apierror.Detail{}
+	}
+
+	return apierror.Detail{
+		Code:    errResp.Detail.Status,
+		Message: errResp.Detail.Message,
+	}
+}
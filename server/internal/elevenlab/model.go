@@ -10,3 +10,12 @@ type VoiceSetting struct {
 	Stability       float32 `json:"stability"`
 	SimilarityBoost float32 `json:"similarity_boost"`
 }
+
+type ErrorResponse struct {
+	Detail ErrorDetail `json:"detail"`
+}
+
+type ErrorDetail struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var (
+	// kbps by MPEG version (1, 2 and 2.5) and layer, index 0 is free format
+	mp3Bitrates = map[[2]int][16]int{
+		{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+
+	mp3SampleRates = map[int][3]int{
+		1: {44100, 48000, 32000},
+		2: {22050, 24000, 16000},
+		3: {11025, 12000, 8000},
+	}
+)
+
+// Duration measures MP3 and WAV audio in seconds, 0 for other formats or
+// audio it cannot read.
+func Duration(content []byte, contentType string) float64 {
+	switch contentType {
+	case "audio/mpeg":
+		return mp3Duration(content)
+	case "audio/wav":
+		return wavDuration(content)
+	}
+
+	return 0
+}
+
+// mp3Duration adds up the frames, so variable bitrates are measured right.
+func mp3Duration(content []byte) float64 {
+	content = skipID3(content)
+
+	var seconds float64
+	for i := 0; i+4 <= len(content); {
+		if bytes.HasPrefix(content[i:], []byte("TAG")) {
+			break
+		}
+
+		size, frameSeconds := mp3Frame(content[i : i+4])
+		if size == 0 {
+			i++ // resync on the next frame header
+
+			continue
+		}
+
+		seconds += frameSeconds
+		i += size
+	}
+
+	return seconds
+}
+
+// mp3Frame reads a frame header and returns the frame's size in bytes and
+// duration in seconds, size 0 when it is not a valid header.
+func mp3Frame(header []byte) (int, float64) {
+	if header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return 0, 0
+	}
+
+	var version int
+	switch (header[1] >> 3) & 0x03 {
+	case 0x03:
+		version = 1
+	case 0x02:
+		version = 2
+	case 0x00:
+		version = 3 // MPEG 2.5
+	default:
+		return 0, 0
+	}
+
+	layer := 4 - int((header[1]>>1)&0x03)
+	if layer == 4 {
+		return 0, 0
+	}
+
+	bitrates := mp3Bitrates[[2]int{min(version, 2), layer}]
+	bitrate := bitrates[header[2]>>4] * 1000
+
+	rateIndex := (header[2] >> 2) & 0x03
+	if bitrate == 0 || rateIndex == 0x03 {
+		return 0, 0
+	}
+
+	sampleRate := mp3SampleRates[version][rateIndex]
+	padding := int((header[2] >> 1) & 0x01)
+
+	var size, samples int
+	switch {
+	case layer == 1:
+		size, samples = (12*bitrate/sampleRate+padding)*4, 384
+	case layer == 3 && version != 1:
+		size, samples = 72*bitrate/sampleRate+padding, 576
+	default:
+		size, samples = 144*bitrate/sampleRate+padding, 1152
+	}
+
+	return size, float64(samples) / float64(sampleRate)
+}
+
+func skipID3(content []byte) []byte {
+	if len(content) < 10 || !bytes.HasPrefix(content, []byte("ID3")) {
+		return content
+	}
+
+	// the size is syncsafe, 7 bits per byte
+	size := int(content[6])<<21 | int(content[7])<<14 | int(content[8])<<7 | int(content[9])
+	size += 10
+	if content[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+
+	if size > len(content) {
+		return nil
+	}
+
+	return content[size:]
+}
+
+// wavDuration divides the size of the data chunk by the byte rate.
+func wavDuration(content []byte) float64 {
+	if len(content) < 12 || string(content[0:4]) != "RIFF" || string(content[8:12]) != "WAVE" {
+		return 0
+	}
+
+	var byteRate uint32
+	for i := 12; i+8 <= len(content); {
+		id := string(content[i : i+4])
+		size := int(binary.LittleEndian.Uint32(content[i+4 : i+8]))
+
+		switch {
+		case id == "fmt " && i+20 <= len(content):
+			byteRate = binary.LittleEndian.Uint32(content[i+16 : i+20])
+		case id == "data" && byteRate > 0:
+			return float64(min(size, len(content)-i-8)) / float64(byteRate)
+		}
+
+		i += 8 + size + size%2
+	}
+
+	return 0
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	FORMAT_MARKDOWN = "md"
+	FORMAT_JSON     = "json"
+	FORMAT_PDF      = "pdf"
+	FORMAT_WEBVTT   = "vtt"
+
+	SPEAKER_INTERVIEWER = "interviewer"
+	SPEAKER_CANDIDATE   = "candidate"
+
+	timeLayout = "2006-01-02 15:04:05 UTC"
+)
+
+var Formats = []string{FORMAT_MARKDOWN, FORMAT_JSON, FORMAT_PDF, FORMAT_WEBVTT}
+
+var contentTypes = map[string]string{
+	FORMAT_MARKDOWN: "text/markdown; charset=utf-8",
+	FORMAT_JSON:     "application/json",
+	FORMAT_PDF:      "application/pdf",
+	FORMAT_WEBVTT:   "text/vtt; charset=utf-8",
+}
+
+// Session is an interview as it is exported.
+type Session struct {
+	ID          string     `json:"id"`
+	Role        string     `json:"role"`
+	Skills      []string   `json:"skills"`
+	Language    string     `json:"language"`
+	Interviewer string     `json:"interviewer"`
+	Mode        string     `json:"mode,omitempty"`
+	Status      string     `json:"status"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	EndedAt     *time.Time `json:"endedAt,omitempty"`
+	Turns       []Turn     `json:"turns"`
+	// Feedback is the interviewer's closing feedback, nil until the
+	// interview ended with one
+	Feedback *Turn `json:"feedback,omitempty"`
+}
+
+type Turn struct {
+	EntryID   string     `json:"entryId"`
+	Seq       int        `json:"seq"`
+	Speaker   string     `json:"speaker"`
+	Text      string     `json:"text"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	// Duration of the turn's audio in seconds, 0 when it has none or it is
+	// unknown
+	Duration     float64 `json:"duration,omitempty"`
+	AudioURL     string  `json:"audioUrl,omitempty"`
+	RecordingURL string  `json:"recordingUrl,omitempty"`
+}
+
+// Render writes the session in one of the Formats.
+func Render(format string, session *Session) ([]byte, error) {
+	switch format {
+	case FORMAT_MARKDOWN:
+		return Markdown(session), nil
+	case FORMAT_JSON:
+		return json.MarshalIndent(session, "", "  ")
+	case FORMAT_PDF:
+		return PDF(session)
+	case FORMAT_WEBVTT:
+		return WebVTT(session), nil
+	}
+
+	return nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// turns returns the turns followed by the feedback.
+func (s *Session) turns() []Turn {
+	if s.Feedback == nil {
+		return s.Turns
+	}
+
+	return append(s.Turns[:len(s.Turns):len(s.Turns)], *s.Feedback)
+}
+
+func (s *Session) speaker(turn Turn) string {
+	if turn.Speaker == SPEAKER_INTERVIEWER {
+		return s.Interviewer
+	}
+
+	return "Candidate"
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(timeLayout)
+}
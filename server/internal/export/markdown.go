@@ -0,0 +1,74 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the session as a readable document.
+func Markdown(s *Session) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Interview: %s\n\n", s.Role)
+	for _, field := range s.details() {
+		fmt.Fprintf(&b, "- **%s**: %s\n", field[0], field[1])
+	}
+
+	b.WriteString("\n## Transcript\n")
+	for _, turn := range s.Turns {
+		s.writeMarkdownTurn(&b, turn)
+	}
+
+	if s.Feedback != nil {
+		b.WriteString("\n## Feedback\n")
+		s.writeMarkdownTurn(&b, *s.Feedback)
+	}
+
+	return []byte(b.String())
+}
+
+func (s *Session) writeMarkdownTurn(b *strings.Builder, turn Turn) {
+	fmt.Fprintf(b, "\n### %s", s.speaker(turn))
+	if turn.CreatedAt != nil {
+		fmt.Fprintf(b, " (%s)", formatTime(turn.CreatedAt))
+	}
+	fmt.Fprintf(b, "\n\n%s\n", strings.TrimSpace(turn.Text))
+
+	var links []string
+	if turn.AudioURL != "" {
+		links = append(links, fmt.Sprintf("[Audio](%s)", turn.AudioURL))
+	}
+	if turn.RecordingURL != "" {
+		links = append(links, fmt.Sprintf("[Recording](%s)", turn.RecordingURL))
+	}
+
+	if len(links) > 0 {
+		fmt.Fprintf(b, "\n%s\n", strings.Join(links, " · "))
+	}
+}
+
+// details are the session's label and value pairs shown above the
+// transcript.
+func (s *Session) details() [][2]string {
+	details := [][2]string{
+		{"Skills", strings.Join(s.Skills, ", ")},
+		{"Language", s.Language},
+		{"Interviewer", s.Interviewer},
+	}
+
+	if s.Mode != "" {
+		details = append(details, [2]string{"Mode", s.Mode})
+	}
+
+	details = append(details, [2]string{"Status", s.Status})
+
+	if s.StartedAt != nil {
+		details = append(details, [2]string{"Started", formatTime(s.StartedAt)})
+	}
+
+	if s.EndedAt != nil {
+		details = append(details, [2]string{"Ended", formatTime(s.EndedAt)})
+	}
+
+	return details
+}
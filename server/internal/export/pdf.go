@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfFont       = "Helvetica"
+	pdfLineHeight = 5.5
+)
+
+// PDF renders the session as a printable document. The core fonts only
+// cover Windows-1252, other characters are replaced.
+func PDF(s *Session) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetTitle(tr("Interview: "+s.Role), false)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.MultiCell(0, 8, tr("Interview: "+s.Role), "", "L", false)
+	pdf.Ln(2)
+
+	for _, field := range s.details() {
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(30, pdfLineHeight, tr(field[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.MultiCell(0, pdfLineHeight, tr(field[1]), "", "L", false)
+	}
+
+	pdfHeading(pdf, tr("Transcript"))
+	for _, turn := range s.Turns {
+		s.writePDFTurn(pdf, tr, turn)
+	}
+
+	if s.Feedback != nil {
+		pdfHeading(pdf, tr("Feedback"))
+		s.writePDFTurn(pdf, tr, *s.Feedback)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func pdfHeading(pdf *gofpdf.Fpdf, text string) {
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "B", 13)
+	pdf.MultiCell(0, 7, text, "", "L", false)
+}
+
+func (s *Session) writePDFTurn(pdf *gofpdf.Fpdf, tr func(string) string, turn Turn) {
+	pdf.Ln(2)
+
+	heading := s.speaker(turn)
+	if turn.CreatedAt != nil {
+		heading += " (" + formatTime(turn.CreatedAt) + ")"
+	}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.MultiCell(0, pdfLineHeight, tr(heading), "", "L", false)
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.MultiCell(0, pdfLineHeight, tr(strings.TrimSpace(turn.Text)), "", "L", false)
+}
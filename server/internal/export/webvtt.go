@@ -0,0 +1,56 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// turns without audio are given the time it takes to read them
+const charactersPerSecond = 15.0
+
+var cueEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// WebVTT renders captions for the session's audio played back to back, one
+// cue per turn.
+func WebVTT(s *Session) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+
+	var start float64
+	for i, turn := range s.turns() {
+		end := start + cueDuration(turn)
+
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n<v %s>%s\n", i+1, timestamp(start), timestamp(end), cueEscaper.Replace(s.speaker(turn)), cueText(turn.Text))
+
+		start = end
+	}
+
+	return []byte(b.String())
+}
+
+func cueDuration(turn Turn) float64 {
+	if turn.Duration > 0 {
+		return turn.Duration
+	}
+
+	return max(float64(utf8.RuneCountInString(turn.Text))/charactersPerSecond, 1)
+}
+
+// cueText escapes the text and drops blank lines, which would end the cue.
+func cueText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, cueEscaper.Replace(strings.ReplaceAll(line, "-->", "->")))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func timestamp(seconds float64) string {
+	millis := int64(seconds*1000 + 0.5)
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
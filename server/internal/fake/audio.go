@@ -0,0 +1,46 @@
+package fake
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/madeindra/mock-interview/server/internal/vad"
+)
+
+const (
+	// a silent MPEG-1 Layer III frame at 32 kbps, 44.1 kHz, mono
+	mp3FrameSize     = 104
+	mp3FrameDuration = 1152.0 / 44100.0
+
+	wavSampleRate = 16000
+
+	// roughly how fast the fake interviewer speaks
+	charactersPerSecond = 15.0
+)
+
+var mp3FrameHeader = []byte{0xFF, 0xFB, 0x10, 0xC0}
+
+// speechDuration estimates how long speaking text takes, in seconds.
+func speechDuration(text string) float64 {
+	seconds := float64(utf8.RuneCountInString(text)) / charactersPerSecond
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
+
+// SilentMP3 returns an MP3 stream of silence lasting at least seconds.
+func SilentMP3(seconds float64) []byte {
+	frames := int(seconds/mp3FrameDuration) + 1
+
+	frame := make([]byte, mp3FrameSize)
+	copy(frame, mp3FrameHeader)
+
+	return bytes.Repeat(frame, frames)
+}
+
+// SilentWAV returns a 16 kHz mono PCM WAV file of silence lasting seconds long.
+func SilentWAV(seconds float64) []byte {
+	return vad.EncodeWAV(make([]int16, int(seconds*wavSampleRate)), wavSampleRate)
+}
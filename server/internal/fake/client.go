@@ -0,0 +1,181 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/usage"
+)
+
+// AI is an offline openai.Client that replies from a script and never
+// makes network calls.
+type AI struct {
+	script Script
+}
+
+const (
+	providerName    = "fake"
+	chatModel       = "fake-chat"
+	transcriptModel = "fake-transcribe"
+	ttsModel        = "fake-tts"
+
+	// uploads are assumed to be 32 kbps audio when estimating their duration
+	uploadBytesPerSecond = 4000
+)
+
+func NewAI(script Script) *AI {
+	return &AI{script: script}
+}
+
+func (c *AI) IsKeyValid(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (c *AI) Status(ctx context.Context) (openai.Status, error) {
+	return openai.STATUS_OPERATIONAL, nil
+}
+
+// Chat picks the reply by counting the interviewer's turns so far, which
+// keeps a session deterministic regardless of the text that was sent and of
+// prompts injected as user messages, such as the end prompt.
+func (c *AI) Chat(ctx context.Context, messages []openai.ChatMessage) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var answers, turns int
+	for _, message := range messages {
+		switch message.Role {
+		case openai.ROLE_USER:
+			answers++
+		case openai.ROLE_ASSISTANT:
+			turns++
+		}
+	}
+
+	if answers == 0 {
+		return "", fmt.Errorf("no user message to reply to")
+	}
+
+	if messages[0].Role == openai.ROLE_SYSTEM && messages[0].Content == openai.GetSummaryPrompt() {
+		summary := fmt.Sprintf("The candidate answered %d earlier questions.", strings.Count(messages[len(messages)-1].Content, "Candidate:"))
+		recordChat(ctx, messages, summary)
+
+		return summary, nil
+	}
+
+	// the greeting is the first turn
+	index := max(turns-1, 0) % len(c.script.Replies)
+	reply := c.script.Replies[index]
+
+	recordChat(ctx, messages, reply)
+
+	return reply, nil
+}
+
+// ChatStream emits the scripted reply word by word.
+func (c *AI) ChatStream(ctx context.Context, messages []openai.ChatMessage, onDelta func(string) error) (string, error) {
+	text, err := c.Chat(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+
+	words := strings.SplitAfter(text, " ")
+	for _, word := range words {
+		if err := onDelta(word); err != nil {
+			return "", err
+		}
+	}
+
+	return text, nil
+}
+
+// ChatJSON answers with the simplest JSON matching the schema.
+func (c *AI) ChatJSON(ctx context.Context, messages []openai.ChatMessage, schema openai.JSONSchema) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var definition map[string]any
+	if err := json.Unmarshal(schema.Schema, &definition); err != nil {
+		return "", err
+	}
+
+	example, err := json.Marshal(exampleValue(definition))
+	if err != nil {
+		return "", err
+	}
+
+	recordChat(ctx, messages, string(example))
+
+	return string(example), nil
+}
+
+func (c *AI) TextToSpeech(ctx context.Context, input string, opts openai.SpeechOptions) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	usage.Add(ctx, usage.Record{
+		Kind:       usage.KIND_SPEECH,
+		Provider:   providerName,
+		Model:      ttsModel,
+		Characters: utf8.RuneCountInString(input),
+	})
+
+	return io.NopCloser(bytes.NewReader(SilentMP3(speechDuration(input)))), nil
+}
+
+func (c *AI) Transcribe(ctx context.Context, file io.ReadCloser, filename, language string) (openai.TranscriptResponse, error) {
+	if file == nil {
+		return openai.TranscriptResponse{}, fmt.Errorf("audio is nil")
+	}
+	defer file.Close()
+
+	if err := ctx.Err(); err != nil {
+		return openai.TranscriptResponse{}, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return openai.TranscriptResponse{}, err
+	}
+
+	duration := float64(size) / uploadBytesPerSecond
+	usage.Add(ctx, usage.Record{
+		Kind:         usage.KIND_TRANSCRIPTION,
+		Provider:     providerName,
+		Model:        transcriptModel,
+		AudioSeconds: duration,
+	})
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if text, ok := c.script.Transcripts[sum]; ok {
+		return openai.TranscriptResponse{Text: text, Duration: duration}, nil
+	}
+
+	return openai.TranscriptResponse{Text: fmt.Sprintf("This is my answer recorded in %s.", sum[:8]), Duration: duration}, nil
+}
+
+func recordChat(ctx context.Context, messages []openai.ChatMessage, reply string) {
+	usage.Add(ctx, usage.Record{
+		Kind:         usage.KIND_CHAT,
+		Provider:     providerName,
+		Model:        chatModel,
+		InputTokens:  openai.CountAllTokens(messages),
+		OutputTokens: openai.CountTokens(openai.ChatMessage{Content: reply}),
+	})
+}
+
+func (c *AI) SSML(ctx context.Context, text string) (string, error) {
+	return fmt.Sprintf("<speak>%s</speak>", text), nil
+}
@@ -0,0 +1,33 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"unicode/utf8"
+
+	"github.com/madeindra/mock-interview/server/internal/elevenlab"
+	"github.com/madeindra/mock-interview/server/internal/usage"
+)
+
+// ElevenLab is an offline elevenlab.Client that speaks silence.
+type ElevenLab struct{}
+
+func NewElevenLab() *ElevenLab {
+	return &ElevenLab{}
+}
+
+func (c *ElevenLab) TextToSpeech(ctx context.Context, input string, opts elevenlab.SpeechOptions) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	usage.Add(ctx, usage.Record{
+		Kind:       usage.KIND_SPEECH,
+		Provider:   "fake-elevenlab",
+		Model:      ttsModel,
+		Characters: utf8.RuneCountInString(input),
+	})
+
+	return io.NopCloser(bytes.NewReader(SilentWAV(speechDuration(input)))), nil
+}
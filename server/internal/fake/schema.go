@@ -0,0 +1,32 @@
+package fake
+
+// exampleValue builds a value matching a JSON schema: the middle value of an
+// enum, a placeholder string, one item per array.
+func exampleValue(schema map[string]any) any {
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[len(enum)/2]
+	}
+
+	switch schema["type"] {
+	case "object":
+		object := make(map[string]any)
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range properties {
+			definition, _ := property.(map[string]any)
+			object[name] = exampleValue(definition)
+		}
+
+		return object
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		return []any{exampleValue(items)}
+	case "string":
+		return "This is a scripted example."
+	case "integer", "number":
+		return 3
+	case "boolean":
+		return true
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package fake
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Script drives the fake AI. Replies are returned in order, one per
+// interviewer turn after the greeting, and Transcripts maps the hex SHA-256
+// of an uploaded audio file to the text returned by Transcribe.
+type Script struct {
+	Replies     []string          `json:"replies"`
+	Transcripts map[string]string `json:"transcripts"`
+}
+
+var defaultReplies = []string{
+	"Thank you for the introduction. Could you walk me through a recent project you are proud of?",
+	"That sounds interesting. What was the hardest problem you had to solve in that project?",
+	"How did you work with your team when you disagreed on the approach?",
+	"What would you like to improve about yourself in your next role?",
+	"Thank you for your time. You communicated clearly and gave concrete examples, try to quantify the impact of your work more often.",
+}
+
+func DefaultScript() Script {
+	return Script{
+		Replies:     defaultReplies,
+		Transcripts: map[string]string{},
+	}
+}
+
+func LoadScript(path string) (Script, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return Script{}, err
+	}
+
+	script := DefaultScript()
+	if err := json.Unmarshal(file, &script); err != nil {
+		return Script{}, err
+	}
+
+	if len(script.Replies) == 0 {
+		script.Replies = defaultReplies
+	}
+
+	return script, nil
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/madeindra/mock-interview/server/internal/blob"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+// answer is the candidate's turn, either a recording to transcribe or typed
+// text. TextOnly asks for a reply without speech.
+type answer struct {
+	recording   []byte
+	contentType string
+	filename    string
+	// duration of the recording in seconds, known once transcribed
+	duration float64
+
+	text     string
+	textOnly bool
+}
+
+// readAnswer reads a JSON body with the typed answer, or a multipart form
+// with the recording as file and an optional textOnly field.
+func readAnswer(req *http.Request) (*answer, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var answerRequest model.AnswerChatRequest
+		if err := json.NewDecoder(req.Body).Decode(&answerRequest); err != nil {
+			return nil, fmt.Errorf("failed to read request: %w", err)
+		}
+
+		text := strings.TrimSpace(answerRequest.Text)
+		if text == "" {
+			return nil, fmt.Errorf("required text is missing")
+		}
+
+		return &answer{text: text, textOnly: answerRequest.TextOnly}, nil
+	}
+
+	file, fileHeader, err := req.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	if fileHeader == nil {
+		return nil, fmt.Errorf("required file is missing")
+	}
+
+	recording, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	textOnly, _ := strconv.ParseBool(req.FormValue("textOnly"))
+
+	return &answer{
+		recording:   recording,
+		contentType: recordingType(fileHeader.Header.Get("Content-Type"), fileHeader.Filename, recording),
+		filename:    fileHeader.Filename,
+		textOnly:    textOnly,
+	}, nil
+}
+
+// transcript returns the typed answer as is, and transcribes a recording.
+func (h *handler) transcript(ctx context.Context, a *answer, pack *language.Pack) (string, error) {
+	if a.recording == nil {
+		return a.text, nil
+	}
+
+	transcript, err := util.TranscribeSpeech(ctx, h.ai, io.NopCloser(bytes.NewReader(a.recording)), a.filename, pack.Transcription)
+	if err != nil {
+		return "", err
+	}
+
+	a.duration = transcript.Duration
+
+	return transcript.Text, nil
+}
+
+// storeRecording keeps the candidate's recording so it can be listened back
+// to, and returns its key, empty for typed answers.
+func (h *handler) storeRecording(ctx context.Context, a *answer) (string, error) {
+	if a.recording == nil {
+		return "", nil
+	}
+
+	key := "recordings/" + uuid.New().String() + blob.Extension(a.contentType)
+	if err := h.blobs.Put(ctx, key, a.contentType, a.recording); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// recordingType trusts the declared audio type, then the file extension,
+// and sniffs the content last.
+func recordingType(declared, filename string, content []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(declared); err == nil && strings.HasPrefix(mediaType, "audio/") {
+		return mediaType
+	}
+
+	if contentType := blob.ContentType(filename); strings.HasPrefix(contentType, "audio/") {
+		return contentType
+	}
+
+	return audioType(content)
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+
+	"github.com/madeindra/mock-interview/server/internal/blob"
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/export"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+const (
+	audioPathPrefix     = "/chat/audio/"
+	recordingPathPrefix = "/chat/recording/"
+)
+
+// storeAudio moves base64 audio into the blob store and returns its key and
+// duration in seconds, empty when there is no audio.
+func (h *handler) storeAudio(ctx context.Context, audio string) (string, float64, error) {
+	if audio == "" {
+		return "", 0, nil
+	}
+
+	content, err := base64.StdEncoding.DecodeString(audio)
+	if err != nil {
+		return "", 0, err
+	}
+
+	contentType := audioType(content)
+	key := "audio/" + uuid.New().String() + blob.Extension(contentType)
+
+	if err := h.blobs.Put(ctx, key, contentType, content); err != nil {
+		return "", 0, err
+	}
+
+	return key, export.Duration(content, contentType), nil
+}
+
+// discardBlobs deletes blobs written for entries that were never stored. It
+// does not use the request's context, which is cancelled when the client
+// goes away.
+func (h *handler) discardBlobs(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		if err := h.blobs.Delete(context.Background(), key); err != nil {
+			log.Printf("failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// responseAudio returns the audio of an entry as the response carries it,
+// inline or as the URL it is served from.
+func (h *handler) responseAudio(entryID, audio string) (string, string) {
+	if !h.audioURLs || audio == "" {
+		return audio, ""
+	}
+
+	return "", audioPathPrefix + entryID
+}
+
+// recordingURL is where the recording of an answer is downloaded from,
+// empty for typed answers.
+func recordingURL(entry data.Entry) string {
+	if entry.AudioKey == "" {
+		return ""
+	}
+
+	return recordingPathPrefix + entry.ID
+}
+
+// GetAudio serves the audio of one of the session's entries, with range
+// requests so players can seek.
+func (h *handler) GetAudio(w http.ResponseWriter, req *http.Request) {
+	entry, ok := h.getEntry(w, req)
+	if !ok {
+		return
+	}
+
+	var content io.ReadSeeker
+	var contentType string
+	var modTime time.Time
+
+	switch {
+	case entry.AudioKey != "":
+		object, ok := h.getBlob(w, req, entry.AudioKey)
+		if !ok {
+			return
+		}
+		defer object.Close()
+
+		content, contentType, modTime = object, object.ContentType, object.ModTime
+	case entry.Audio != "":
+		// stored inline before the blob store
+		decoded, err := base64.StdEncoding.DecodeString(entry.Audio)
+		if err != nil {
+			log.Printf("failed to decode audio: %v", err)
+			util.SendResponse(w, nil, "failed to get audio", http.StatusInternalServerError)
+
+			return
+		}
+
+		content, contentType = bytes.NewReader(decoded), audioType(decoded)
+	default:
+		util.SendResponse(w, nil, "audio not found", http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, req, "", modTime, content)
+}
+
+// GetRecording downloads the candidate's own recording of an answer.
+func (h *handler) GetRecording(w http.ResponseWriter, req *http.Request) {
+	entry, ok := h.getEntry(w, req)
+	if !ok {
+		return
+	}
+
+	if entry.Role != string(openai.ROLE_USER) || entry.AudioKey == "" {
+		util.SendResponse(w, nil, "recording not found", http.StatusNotFound)
+
+		return
+	}
+
+	object, ok := h.getBlob(w, req, entry.AudioKey)
+	if !ok {
+		return
+	}
+	defer object.Close()
+
+	contentType := entry.AudioFormat
+	if contentType == "" {
+		contentType = object.ContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "answer-" + entry.ID + blob.Extension(contentType),
+	}))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, req, "", object.ModTime, object)
+}
+
+// getEntry returns the entry in the URL, answering not found when it
+// belongs to another session.
+func (h *handler) getEntry(w http.ResponseWriter, req *http.Request) (*data.Entry, bool) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return nil, false
+	}
+
+	entry, err := h.db.GetChat(chi.URLParam(req, "entryID"))
+	if errors.Is(err, data.ErrNotFound) || (err == nil && entry.ChatUserID != user.ID) {
+		util.SendResponse(w, nil, "audio not found", http.StatusNotFound)
+
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("failed to get chat: %v", err)
+		util.SendResponse(w, nil, "failed to get audio", http.StatusInternalServerError)
+
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func (h *handler) getBlob(w http.ResponseWriter, req *http.Request, key string) (*blob.Object, bool) {
+	object, err := h.blobs.Get(req.Context(), key)
+	if errors.Is(err, blob.ErrNotFound) {
+		util.SendResponse(w, nil, "audio not found", http.StatusNotFound)
+
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("failed to get audio: %v", err)
+		util.SendResponse(w, nil, "failed to get audio", http.StatusInternalServerError)
+
+		return nil, false
+	}
+
+	return object, true
+}
+
+// audioType recognizes the formats the speech engines return, MP3 when
+// nothing else matches.
+func audioType(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("RIFF")) && len(content) >= 12 && string(content[8:12]) == "WAVE":
+		return "audio/wav"
+	case bytes.HasPrefix(content, []byte("OggS")):
+		return "audio/ogg"
+	case bytes.HasPrefix(content, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(content, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "audio/webm"
+	default:
+		return "audio/mpeg"
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/madeindra/mock-interview/server/internal/budget"
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+const (
+	apiKeyHeader       = "X-API-Key"
+	forwardedForHeader = "X-Forwarded-For"
+)
+
+// clientIdentifier identifies who a session's spending is charged to.
+type clientIdentifier struct {
+	keys    map[string]bool
+	proxies []*net.IPNet
+}
+
+// newClientIdentifier honors only the given API keys, and X-Forwarded-For
+// only when sent by one of the given proxies, IPs or CIDRs.
+func newClientIdentifier(keys, proxies []string) (*clientIdentifier, error) {
+	c := &clientIdentifier{keys: make(map[string]bool, len(keys))}
+
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			c.keys[key] = true
+		}
+	}
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+
+		c.proxies = append(c.proxies, network)
+	}
+
+	return c, nil
+}
+
+// id is the configured API key when the client sends one, its IP address
+// otherwise. Keys are hashed so they are never stored, and unknown keys
+// are ignored so a client cannot escape its daily cap by inventing them.
+func (c *clientIdentifier) id(req *http.Request) string {
+	if key := req.Header.Get(apiKeyHeader); key != "" && c.keys[key] {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+
+	return "ip:" + c.ip(req)
+}
+
+// ip walks X-Forwarded-For from the nearest hop while it is a trusted
+// proxy, the first address that is not is the client's.
+func (c *clientIdentifier) ip(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if !c.trusted(host) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range req.Header.Values(forwardedForHeader) {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		host = hop
+		if !c.trusted(hop) {
+			break
+		}
+	}
+
+	return host
+}
+
+func (c *clientIdentifier) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range c.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// budgetError returns the code and message, in the session's language, to
+// report for a failed budget check. The code is empty when the check itself
+// failed.
+func budgetError(err error, pack *language.Pack) (string, string) {
+	var budgetErr *budget.Error
+	if errors.As(err, &budgetErr) {
+		return budgetErr.Code, pack.Message(budgetErr.Code, budgetErr.Error())
+	}
+
+	log.Printf("failed to check budget: %v", err)
+
+	return "", "failed to check budget"
+}
+
+func sendBudgetError(w http.ResponseWriter, pack *language.Pack, data any, err error) {
+	code, message := budgetError(err, pack)
+	if code == "" {
+		util.SendResponse(w, data, message, http.StatusInternalServerError)
+
+		return
+	}
+
+	util.SendError(w, data, code, message, http.StatusTooManyRequests)
+}
+
+func countAnswers(entries []data.Entry) int {
+	var answers int
+	for _, entry := range entries {
+		if entry.Role == string(openai.ROLE_USER) {
+			answers++
+		}
+	}
+
+	return answers
+}
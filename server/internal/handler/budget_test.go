@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/vad"
+)
+
+// knownKeyHash is the first 8 bytes of the SHA-256 of "known"
+const knownKeyHash = "7117fff2d0fd2944"
+
+func TestClientIdentifier(t *testing.T) {
+	clients, err := newClientIdentifier([]string{"known"}, []string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		apiKey       string
+		forwardedFor string
+		want         string
+	}{
+		{"direct", "203.0.113.7:1234", "", "", "ip:203.0.113.7"},
+		{"unknown key", "203.0.113.7:1234", "invented", "", "ip:203.0.113.7"},
+		{"known key", "203.0.113.7:1234", "known", "", "key:" + knownKeyHash},
+		{"spoofed forwarded for", "203.0.113.7:1234", "", "198.51.100.1", "ip:203.0.113.7"},
+		{"behind proxy", "10.1.2.3:1234", "", "198.51.100.1", "ip:198.51.100.1"},
+		{"client prepends a hop", "10.1.2.3:1234", "", "198.51.100.1, 203.0.113.9", "ip:203.0.113.9"},
+		{"proxy chain", "10.1.2.3:1234", "", "203.0.113.9, 192.168.1.1", "ip:203.0.113.9"},
+		{"proxy without header", "10.1.2.3:1234", "", "", "ip:10.1.2.3"},
+		{"garbage hop", "10.1.2.3:1234", "", "not-an-ip", "ip:10.1.2.3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/chat/start", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.apiKey != "" {
+				req.Header.Set(apiKeyHeader, test.apiKey)
+			}
+			if test.forwardedFor != "" {
+				req.Header.Set(forwardedForHeader, test.forwardedFor)
+			}
+
+			if got := clients.id(req); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClientIdentifierInvalidProxy(t *testing.T) {
+	if _, err := newClientIdentifier(nil, []string{"not-a-network"}); err == nil {
+		t.Error("got no error for an invalid proxy")
+	}
+}
+
+// The recording being answered with counts against the audio budget,
+// measured before it is transcribed when its format allows, and once it is
+// transcribed otherwise.
+func TestAudioBudgetCountsRecording(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BudgetMaxAudioSeconds = 10
+
+	server := httptest.NewServer(NewHandler(cfg))
+	t.Cleanup(server.Close)
+
+	s := startSession(t, server, model.StartChatRequest{Role: "Backend Engineer", Skills: []string{"Go"}})
+
+	// at 1 kHz a second of audio is 2000 bytes
+	const sampleRate = 1000
+
+	long := s.answerFile("answer.wav", vad.EncodeWAV(make([]int16, 30*sampleRate), sampleRate), http.StatusTooManyRequests)
+	if long.Prompt.Text != "" {
+		t.Errorf("got transcript %q, want the recording refused before it is transcribed", long.Prompt.Text)
+	}
+
+	s.answerFile("answer.wav", vad.EncodeWAV(make([]int16, 5*sampleRate), sampleRate), http.StatusOK)
+
+	// webm is not measured, the fake transcription takes it for 15 seconds
+	unmeasured := s.answerFile("answer.webm", bytes.Repeat([]byte{1}, 60000), http.StatusTooManyRequests)
+	if unmeasured.Prompt.Text == "" {
+		t.Error("got no transcript, want it sent back with the refusal")
+	}
+}
@@ -4,17 +4,23 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/madeindra/mock-interview/server/internal/config"
 	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/language"
 	"github.com/madeindra/mock-interview/server/internal/middleware"
 	"github.com/madeindra/mock-interview/server/internal/model"
 	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/persona"
+	"github.com/madeindra/mock-interview/server/internal/session"
 	"github.com/madeindra/mock-interview/server/internal/util"
 )
 
-func (h *handler) Status(w http.ResponseWriter, _ *http.Request) {
-	isKeyValid, err := h.ai.IsKeyValid()
+func (h *handler) Status(w http.ResponseWriter, req *http.Request) {
+	isKeyValid, err := h.ai.IsKeyValid(req.Context())
 	if err != nil {
 		log.Printf("failed to check key validity: %v", err)
 		util.SendResponse(w, nil, "failed to check key validity", http.StatusInternalServerError)
@@ -22,7 +28,7 @@ func (h *handler) Status(w http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
-	status, err := h.ai.Status()
+	status, err := h.ai.Status(req.Context())
 	if err != nil {
 		log.Printf("failed to check API availability: %v", err)
 		util.SendResponse(w, nil, "failed to check API availability", http.StatusInternalServerError)
@@ -43,11 +49,17 @@ func (h *handler) Status(w http.ResponseWriter, _ *http.Request) {
 
 	apiStatus := util.Pointer(string(status))
 
+	circuits := make(map[string]string, len(h.breakers))
+	for _, breaker := range h.breakers {
+		circuits[breaker.Name()] = string(breaker.State())
+	}
+
 	response := model.StatusResponse{
 		Server:    true,       // always true when the server is running
 		Key:       isKeyValid, // true if the API key is valid, false otherwise
 		API:       apiState,   // nil if status unknown, true if operational, false otherwise
 		ApiStatus: apiStatus,  // always return the status string
+		Circuits:  circuits,   // closed when healthy, open while calls are being refused
 	}
 
 	util.SendResponse(w, response, "success", http.StatusOK)
@@ -62,12 +74,56 @@ func (h *handler) StartChat(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	chatLanguage := h.ai.GetDefaultTranscriptLanguage()
+	client := h.clients.id(req)
+
+	var chatUserID string
+	defer func() { h.saveUsage(req.Context(), chatUserID, client) }()
+
+	pack := h.packs.Get(language.DEFAULT_LANGUAGE)
 	if startChatRequest.Language != "" {
-		chatLanguage = config.GetLanguage(startChatRequest.Language)
+		var ok bool
+		pack, ok = h.packs.Find(startChatRequest.Language)
+		if !ok {
+			log.Printf("unsupported language: %s", startChatRequest.Language)
+			util.SendResponse(w, nil, "unsupported language", http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	personaID := startChatRequest.Persona
+	if personaID == "" {
+		personaID = persona.DEFAULT_PERSONA
+	}
+
+	interviewer, ok := h.personas.Get(personaID)
+	if !ok {
+		log.Printf("unknown persona: %s", personaID)
+		util.SendResponse(w, nil, "unknown persona", http.StatusBadRequest)
+
+		return
+	}
+
+	if startChatRequest.DurationMinutes < 0 || startChatRequest.MaxQuestions < 0 {
+		log.Println("negative interview limit")
+		util.SendResponse(w, nil, "duration and question limits cannot be negative", http.StatusBadRequest)
+
+		return
+	}
+
+	mode := startChatRequest.Mode
+	if mode == "" {
+		mode = model.MODE_REALISTIC
+	}
+
+	if mode != model.MODE_REALISTIC && mode != model.MODE_PRACTICE {
+		log.Printf("unknown mode: %s", mode)
+		util.SendResponse(w, nil, "unknown mode", http.StatusBadRequest)
+
+		return
 	}
 
-	systempPrompt, initialText, err := util.GetChatAssets(h.ai, startChatRequest.Role, startChatRequest.Skills, chatLanguage)
+	systempPrompt, initialText, err := util.GetChatAssets(h.ai, startChatRequest.Role, startChatRequest.Skills, pack, interviewer)
 	if err != nil {
 		log.Printf("failed to get system prompt or initial text: %v", err)
 		util.SendResponse(w, nil, "failed to prepare chat", http.StatusInternalServerError)
@@ -75,20 +131,44 @@ func (h *handler) StartChat(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	initialAudio, err := util.GenerateSpeech(h.ai, h.el, chatLanguage, initialText)
+	if err := h.budget.CheckClient(req.Context(), client); err != nil {
+		sendBudgetError(w, pack, nil, err)
+
+		return
+	}
+
+	initialAudio, err := util.GenerateSpeech(req.Context(), h.speech, pack.Language, initialText, interviewer.Voices)
 	if err != nil {
 		log.Printf("failed to generate speech: %v", err)
-		util.SendResponse(w, nil, "failed to generate speech", http.StatusInternalServerError)
+		util.SendResponse(w, nil, "failed to generate speech", util.UpstreamStatus(err))
 
 		return
 	}
 
 	var initialSSML string
 	if initialAudio == "" {
-		initialSSML, err = util.GenerateSSML(h.ai, initialText)
-		log.Printf("failed to generate ssml: %v", err)
+		initialSSML, err = util.GenerateSSML(req.Context(), h.ai, initialText)
+		if err != nil {
+			log.Printf("failed to generate ssml: %v", err)
+		}
+	}
+
+	initialAudioKey, initialAudioDuration, err := h.storeAudio(req.Context(), initialAudio)
+	if err != nil {
+		log.Printf("failed to store audio: %v", err)
+		util.SendResponse(w, nil, "failed to store audio", http.StatusInternalServerError)
+
+		return
 	}
 
+	// the blobs are only kept once the entries referencing them are stored
+	stored := false
+	defer func() {
+		if !stored {
+			h.discardBlobs(initialAudioKey)
+		}
+	}()
+
 	plainSecret := util.GenerateRandom()
 	hashed, err := util.CreateHash(plainSecret)
 	if err != nil {
@@ -107,7 +187,19 @@ func (h *handler) StartChat(w http.ResponseWriter, req *http.Request) {
 	}
 	defer tx.Rollback()
 
-	newUser, err := h.db.CreateChatUser(tx, hashed, chatLanguage)
+	newUser, err := tx.CreateChatUser(data.ChatUser{
+		Secret:   hashed,
+		Language: pack.Language,
+		Client:   client,
+		Persona:  interviewer.ID,
+		Role:     startChatRequest.Role,
+		Skills:   startChatRequest.Skills,
+		Scoring:  startChatRequest.Scoring,
+		Mode:     mode,
+
+		Duration:     time.Duration(startChatRequest.DurationMinutes) * time.Minute,
+		MaxQuestions: startChatRequest.MaxQuestions,
+	})
 	if err != nil {
 		log.Printf("failed to create new chat: %v", err)
 		util.SendResponse(w, nil, "failed to create new chat", http.StatusInternalServerError)
@@ -115,17 +207,19 @@ func (h *handler) StartChat(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if _, err := h.db.CreateChats(tx, newUser.ID, []data.Entry{
+	created, err := tx.CreateChats(newUser.ID, []data.Entry{
 		{
 			Role: string(openai.ROLE_SYSTEM),
 			Text: systempPrompt,
 		},
 		{
-			Role:  string(openai.ROLE_ASSISTANT),
-			Text:  initialText,
-			Audio: initialAudio,
+			Role:          string(openai.ROLE_ASSISTANT),
+			Text:          initialText,
+			AudioKey:      initialAudioKey,
+			AudioDuration: initialAudioDuration,
 		},
-	}); err != nil {
+	})
+	if err != nil {
 		log.Printf("failed to create chat: %v", err)
 		util.SendResponse(w, nil, "failed to create chat", http.StatusInternalServerError)
 
@@ -138,15 +232,29 @@ func (h *handler) StartChat(w http.ResponseWriter, req *http.Request) {
 
 		return
 	}
+	stored = true
+	chatUserID = newUser.ID
+
+	initialAudio, initialAudioURL := h.responseAudio(created[1].ID, initialAudio)
+
+	// clients keep using the code they asked for, e.g. "en" rather than "en-US"
+	responseLanguage := startChatRequest.Language
+	if responseLanguage == "" {
+		responseLanguage = pack.Code
+	}
 
 	initialChat := model.StartChatResponse{
 		ID:       newUser.ID,
 		Secret:   plainSecret,
-		Language: startChatRequest.Language,
+		Language: responseLanguage,
+		Persona:  interviewer.ID,
+		Mode:     mode,
+		Progress: progressResponse(progress(newUser, 0)),
 		Chat: model.Chat{
-			Text:  initialText,
-			Audio: initialAudio,
-			SSML:  initialSSML,
+			Text:     initialText,
+			Audio:    initialAudio,
+			AudioURL: initialAudioURL,
+			SSML:     initialSSML,
 		},
 	}
 
@@ -154,91 +262,149 @@ func (h *handler) StartChat(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h *handler) AnswerChat(w http.ResponseWriter, req *http.Request) {
-	userID := req.Context().Value(middleware.ContextKeyUserID).(string)
-	userSecret := req.Context().Value(middleware.ContextKeyUserSecret).(string)
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+	defer h.saveUsage(req.Context(), user.ID, user.Client)
 
-	if userID == "" || userSecret == "" {
-		log.Println("user ID or secret is missing")
-		util.SendResponse(w, nil, "missing required authentication", http.StatusUnauthorized)
+	pack := h.packs.Get(user.Language)
+
+	userAnswer, err := readAnswer(req)
+	if err != nil {
+		log.Printf("failed to read answer: %v", err)
+		util.SendResponse(w, nil, "an audio file or a text answer is required", http.StatusBadRequest)
 
 		return
 	}
 
-	user, err := h.db.GetChatUser(userID)
-	if err != nil {
-		log.Printf("failed to get chat user: %v", err)
-		util.SendResponse(w, nil, "failed to get chat user", http.StatusNotFound)
+	response, turnErr := h.answerTurn(req.Context(), user, userAnswer, turnEvents{})
+	if turnErr != nil {
+		sendTurnError(w, pack, turnErr)
 
 		return
 	}
 
-	if err := util.CompareHash(userSecret, user.Secret); err != nil {
-		log.Println("invalid user secret")
-		util.SendResponse(w, nil, "invalid user secret", http.StatusUnauthorized)
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
 
+func (h *handler) EndChat(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
 		return
 	}
+	defer h.saveUsage(req.Context(), user.ID, user.Client)
+
+	pack := h.packs.Get(user.Language)
+
+	// ending is only possible once, so the feedback is only paid for once
+	if err := session.Transition(user.Status, session.STATUS_ENDED); err != nil {
+		sendSessionError(w, pack, nil, err)
 
-	entries, err := h.db.GetChatsByChatUserID(user.ID)
+		return
+	}
+
+	// the feedback covers every section unless some are picked, e.g. ?sections=strengths,fit
+	sections := language.Sections
+	if value := req.URL.Query().Get("sections"); value != "" {
+		sections = strings.Split(value, ",")
+	}
+
+	for _, section := range sections {
+		if !slices.Contains(language.Sections, section) {
+			log.Printf("unknown feedback section: %s", section)
+			util.SendResponse(w, nil, "unknown feedback section", http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	endPrompt, err := pack.RenderEndPrompt(sections)
 	if err != nil {
-		log.Printf("failed to get chat: %v", err)
-		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
+		log.Printf("failed to render end prompt: %v", err)
+		util.SendResponse(w, nil, "failed to prepare feedback", http.StatusInternalServerError)
 
 		return
 	}
 
-	file, fileHeader, err := req.FormFile("file")
+	entry, err := h.db.GetChatsByChatUserID(user.ID)
 	if err != nil {
-		log.Printf("failed to read file: %v", err)
-		util.SendResponse(w, nil, "failed to read file", http.StatusInternalServerError)
+		log.Printf("failed to get chat: %v", err)
+		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
 
 		return
 	}
-	if fileHeader == nil {
-		log.Println("required file is missing")
-		util.SendResponse(w, nil, "required file is missing", http.StatusBadRequest)
+
+	if err := h.budget.CheckSession(req.Context(), user); err != nil {
+		sendBudgetError(w, pack, nil, err)
 
 		return
 	}
-	defer file.Close()
 
-	transcriptText, err := util.TranscribeSpeech(h.ai, file, fileHeader.Filename, user.Language)
+	history, err := h.history.Build(req.Context(), user.ID, entry)
 	if err != nil {
-		log.Printf("failed to transcribe speech: %v", err)
-		util.SendResponse(w, nil, "failed to transcribe speech", http.StatusInternalServerError)
+		log.Printf("failed to build chat history: %v", err)
+		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
 
 		return
 	}
 
-	history := util.ConvertToChatMessage(entries)
-
 	chatHistory := append(history, openai.ChatMessage{
 		Role:    openai.ROLE_USER,
-		Content: transcriptText,
+		Content: endPrompt,
 	})
 
-	answerText, err := util.GenerateText(h.ai, chatHistory)
+	answerText, err := util.GenerateText(req.Context(), h.ai, chatHistory)
 	if err != nil {
 		log.Printf("failed to get chat completion: %v", err)
-		util.SendResponse(w, nil, "failed to get chat completion", http.StatusInternalServerError)
+		util.SendResponse(w, nil, "failed to get chat completion", util.UpstreamStatus(err))
 
 		return
 	}
 
-	answerAudio, err := util.GenerateSpeech(h.ai, h.el, user.Language, answerText)
-	if err != nil {
-		log.Printf("failed to generate speech: %v", err)
-		util.SendResponse(w, nil, "failed to generate speech", http.StatusInternalServerError)
+	if err := h.budget.CheckSession(req.Context(), user); err != nil {
+		sendBudgetError(w, pack, nil, err)
 
 		return
 	}
 
-	var answerSSML string
-	if answerAudio == "" {
-		answerSSML, err = util.GenerateSSML(h.ai, answerText)
-		log.Printf("failed to generate ssml: %v", err)
+	// ?textOnly=true skips speech synthesis of the feedback
+	textOnly, _ := strconv.ParseBool(req.URL.Query().Get("textOnly"))
+
+	var answerAudio, answerSSML string
+	if !textOnly {
+		answerAudio, err = util.GenerateSpeech(req.Context(), h.speech, user.Language, answerText, h.voices(user))
+		if err != nil {
+			log.Printf("failed to generate speech: %v", err)
+			util.SendResponse(w, nil, "failed to generate speech", util.UpstreamStatus(err))
+
+			return
+		}
+
+		if answerAudio == "" {
+			answerSSML, err = util.GenerateSSML(req.Context(), h.ai, answerText)
+			if err != nil {
+				log.Printf("failed to generate ssml: %v", err)
+			}
+		}
 	}
 
+	answerAudioKey, answerAudioDuration, err := h.storeAudio(req.Context(), answerAudio)
+	if err != nil {
+		log.Printf("failed to store audio: %v", err)
+		util.SendResponse(w, nil, "failed to store audio", http.StatusInternalServerError)
+
+		return
+	}
+
+	// the blobs are only kept once the entries referencing them are stored
+	stored := false
+	defer func() {
+		if !stored {
+			h.discardBlobs(answerAudioKey)
+		}
+	}()
+
 	tx, err := h.db.BeginTx()
 	if err != nil {
 		log.Printf("failed to begin transaction: %v", err)
@@ -248,17 +414,30 @@ func (h *handler) AnswerChat(w http.ResponseWriter, req *http.Request) {
 	}
 	defer tx.Rollback()
 
-	if _, err := h.db.CreateChats(tx, userID, []data.Entry{
-		{
-			Role: string(openai.ROLE_USER),
-			Text: transcriptText,
-		},
+	ended, err := tx.UpdateChatUserStatus(user.ID, session.STATUS_ENDED)
+	if err != nil {
+		log.Printf("failed to end session: %v", err)
+		util.SendResponse(w, nil, "failed to end chat", http.StatusInternalServerError)
+
+		return
+	}
+
+	// a concurrent request ended the session first
+	if !ended {
+		sendSessionError(w, pack, nil, &session.Error{Code: session.CODE_SESSION_ENDED})
+
+		return
+	}
+
+	created, err := tx.CreateChats(user.ID, []data.Entry{
 		{
-			Role:  string(openai.ROLE_ASSISTANT),
-			Text:  answerText,
-			Audio: answerAudio,
+			Role:          string(openai.ROLE_ASSISTANT),
+			Text:          answerText,
+			AudioKey:      answerAudioKey,
+			AudioDuration: answerAudioDuration,
 		},
-	}); err != nil {
+	})
+	if err != nil {
 		log.Printf("failed to create chat: %v", err)
 		util.SendResponse(w, nil, "failed to create chat", http.StatusInternalServerError)
 
@@ -271,23 +450,29 @@ func (h *handler) AnswerChat(w http.ResponseWriter, req *http.Request) {
 
 		return
 	}
+	stored = true
+
+	h.endReport(req.Context(), user)
+
+	answerAudio, answerAudioURL := h.responseAudio(created[0].ID, answerAudio)
 
 	response := model.AnswerChatResponse{
-		Language: config.GetCode(user.Language),
-		Prompt: model.Chat{
-			Text: transcriptText,
-		},
+		Language: pack.Code,
 		Answer: model.Chat{
-			Text:  answerText,
-			Audio: answerAudio,
-			SSML:  answerSSML,
+			Text:     answerText,
+			Audio:    answerAudio,
+			AudioURL: answerAudioURL,
+			SSML:     answerSSML,
 		},
+		Scores: answerScores(entry),
 	}
 
 	util.SendResponse(w, response, "success", http.StatusOK)
 }
 
-func (h *handler) EndChat(w http.ResponseWriter, req *http.Request) {
+// getChatUser authenticates the chat user from the BasicAuth credentials,
+// sending the error response itself when it fails.
+func (h *handler) getChatUser(w http.ResponseWriter, req *http.Request) (*data.ChatUser, bool) {
 	userID := req.Context().Value(middleware.ContextKeyUserID).(string)
 	userSecret := req.Context().Value(middleware.ContextKeyUserSecret).(string)
 
@@ -295,7 +480,7 @@ func (h *handler) EndChat(w http.ResponseWriter, req *http.Request) {
 		log.Println("user ID or secret is missing")
 		util.SendResponse(w, nil, "missing required authentication", http.StatusUnauthorized)
 
-		return
+		return nil, false
 	}
 
 	user, err := h.db.GetChatUser(userID)
@@ -303,84 +488,22 @@ func (h *handler) EndChat(w http.ResponseWriter, req *http.Request) {
 		log.Printf("failed to get chat user: %v", err)
 		util.SendResponse(w, nil, "failed to get chat user", http.StatusNotFound)
 
-		return
+		return nil, false
 	}
 
 	if err := util.CompareHash(userSecret, user.Secret); err != nil {
 		log.Println("invalid user secret")
 		util.SendResponse(w, nil, "invalid user secret", http.StatusUnauthorized)
 
-		return
-	}
-
-	entry, err := h.db.GetChatsByChatUserID(user.ID)
-	if err != nil {
-		log.Printf("failed to get chat: %v", err)
-		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
-
-		return
-	}
-
-	history := util.ConvertToChatMessage(entry)
-
-	chatHistory := append(history, openai.ChatMessage{
-		Role:    openai.ROLE_USER,
-		Content: "That is the end of the mock interview, thank you, please provide your feedbacks on my strength and which area to improve, and whether you are confident that I fits the role.",
-	})
-
-	answerText, err := util.GenerateText(h.ai, chatHistory)
-	if err != nil {
-		log.Printf("failed to get chat completion: %v", err)
-		util.SendResponse(w, nil, "failed to get chat completion", http.StatusInternalServerError)
-
-		return
-	}
-
-	answerAudio, err := util.GenerateSpeech(h.ai, h.el, user.Language, answerText)
-	if err != nil {
-		log.Printf("failed to generate speech: %v", err)
-		util.SendResponse(w, nil, "failed to generate speech", http.StatusInternalServerError)
-
-		return
+		return nil, false
 	}
 
-	var answerSSML string
-	if answerAudio == "" {
-		answerSSML, err = util.GenerateSSML(h.ai, answerText)
-		log.Printf("failed to generate ssml: %v", err)
-	}
-
-	tx, err := h.db.BeginTx()
-	if err != nil {
-		log.Printf("failed to begin transaction: %v", err)
-		util.SendResponse(w, nil, "failed to create new chat", http.StatusInternalServerError)
+	if err := h.expire(req.Context(), user); err != nil {
+		log.Printf("failed to expire session: %v", err)
+		util.SendResponse(w, nil, "failed to get chat user", http.StatusInternalServerError)
 
-		return
+		return nil, false
 	}
-	defer tx.Rollback()
 
-	if _, err := h.db.CreateChat(tx, userID, string(openai.ROLE_ASSISTANT), answerText, answerAudio); err != nil {
-		log.Printf("failed to create chat: %v", err)
-		util.SendResponse(w, nil, "failed to create chat", http.StatusInternalServerError)
-
-		return
-	}
-
-	if err := tx.Commit(); err != nil {
-		log.Printf("failed to commit transaction: %v", err)
-		util.SendResponse(w, nil, "failed to create new chat", http.StatusInternalServerError)
-
-		return
-	}
-
-	response := model.AnswerChatResponse{
-		Language: config.GetCode(user.Language),
-		Answer: model.Chat{
-			Text:  answerText,
-			Audio: answerAudio,
-			SSML:  answerSSML,
-		},
-	}
-
-	util.SendResponse(w, response, "success", http.StatusOK)
+	return user, true
 }
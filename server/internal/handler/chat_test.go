@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/madeindra/mock-interview/server/internal/config"
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/fake"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/provider"
+)
+
+// testConfig runs the API on the fake provider with a fresh database and
+// blob directory.
+func testConfig(t *testing.T) config.AppConfig {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	return config.AppConfig{
+		DBPath:      filepath.Join(dir, "test.db"),
+		LLMProvider: provider.PROVIDER_FAKE,
+		BlobStore:   provider.BLOB_STORE_LOCAL,
+		BlobDir:     filepath.Join(dir, "blobs"),
+	}
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(NewHandler(testConfig(t)))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newTestServerWithStore is newTestServer with the handler's store wrapped,
+// e.g. to change what a request sees.
+func newTestServerWithStore(t *testing.T, wrap func(data.Store) data.Store) *httptest.Server {
+	t.Helper()
+
+	cfg := testConfig(t)
+	h := newHandler(cfg)
+	h.db = wrap(h.db)
+
+	server := httptest.NewServer(h.routes(cfg))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+type testSession struct {
+	t      *testing.T
+	server *httptest.Server
+	id     string
+	secret string
+}
+
+// do sends the request as the session and decodes the response data into v.
+func (s *testSession) do(req *http.Request, wantStatus int, v any) {
+	s.t.Helper()
+
+	if s.id != "" {
+		req.SetBasicAuth(s.id, s.secret)
+	}
+
+	resp, err := s.server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	response := model.Response{Data: v}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		s.t.Fatalf("%s %s: failed to decode response: %v", req.Method, req.URL.Path, err)
+	}
+
+	if resp.StatusCode != wantStatus {
+		s.t.Fatalf("%s %s: got status %d (%s %s), want %d", req.Method, req.URL.Path, resp.StatusCode, response.Code, response.Message, wantStatus)
+	}
+}
+
+func startSession(t *testing.T, server *httptest.Server, request model.StartChatRequest) *testSession {
+	t.Helper()
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/chat/start", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	s := &testSession{t: t, server: server}
+
+	var start model.StartChatResponse
+	s.do(req, http.StatusOK, &start)
+
+	if start.ID == "" || start.Secret == "" {
+		t.Fatalf("start: got id %q secret %q, want both", start.ID, start.Secret)
+	}
+
+	if request.Language != "" && start.Language != request.Language {
+		t.Errorf("start: got language %q, want the requested %q", start.Language, request.Language)
+	}
+
+	s.id, s.secret = start.ID, start.Secret
+
+	return s
+}
+
+func (s *testSession) answerText(text string, wantStatus int) model.AnswerChatResponse {
+	s.t.Helper()
+
+	body, err := json.Marshal(model.AnswerChatRequest{Text: text, TextOnly: true})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/chat/answer", bytes.NewReader(body))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var answer model.AnswerChatResponse
+	s.do(req, wantStatus, &answer)
+
+	return answer
+}
+
+func (s *testSession) answerRecording(recording []byte) model.AnswerChatResponse {
+	s.t.Helper()
+
+	return s.answerFile("answer.webm", recording, http.StatusOK)
+}
+
+func (s *testSession) answerFile(filename string, recording []byte, wantStatus int) model.AnswerChatResponse {
+	s.t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	file.Write(recording)
+	form.Close()
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/chat/answer", &body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	var answer model.AnswerChatResponse
+	s.do(req, wantStatus, &answer)
+
+	return answer
+}
+
+func (s *testSession) end(wantStatus int) model.AnswerChatResponse {
+	s.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, s.server.URL+"/chat/end?textOnly=true", nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	var end model.AnswerChatResponse
+	s.do(req, wantStatus, &end)
+
+	return end
+}
+
+func TestChatFlow(t *testing.T) {
+	server := newTestServer(t)
+	replies := fake.DefaultScript().Replies
+
+	s := startSession(t, server, model.StartChatRequest{
+		Role:     "Backend Engineer",
+		Skills:   []string{"Go"},
+		Language: "en",
+	})
+
+	first := s.answerRecording([]byte("a recorded answer"))
+	if first.Prompt.Text == "" {
+		t.Error("answer: transcript is empty")
+	}
+	if first.Prompt.RecordingURL == "" {
+		t.Error("answer: recording url is empty")
+	}
+	if first.Answer.Text != replies[0] {
+		t.Errorf("answer: got reply %q, want %q", first.Answer.Text, replies[0])
+	}
+
+	second := s.answerText("I built a payment service in Go.", http.StatusOK)
+	if second.Prompt.Text != "I built a payment service in Go." {
+		t.Errorf("answer: got prompt %q, want the typed answer", second.Prompt.Text)
+	}
+	if second.Answer.Text != replies[1] {
+		t.Errorf("answer: got reply %q, want %q", second.Answer.Text, replies[1])
+	}
+
+	end := s.end(http.StatusOK)
+	if end.Answer.Text != replies[2] {
+		t.Errorf("end: got feedback %q, want %q", end.Answer.Text, replies[2])
+	}
+
+	s.answerText("one more thing", http.StatusConflict)
+	s.end(http.StatusConflict)
+}
+
+// The end prompt sent once the question limit is hit must not shift the
+// fake script.
+func TestChatFlowQuestionLimit(t *testing.T) {
+	server := newTestServer(t)
+	replies := fake.DefaultScript().Replies
+
+	s := startSession(t, server, model.StartChatRequest{
+		Role:         "Backend Engineer",
+		Skills:       []string{"Go"},
+		Language:     "en",
+		MaxQuestions: 2,
+	})
+
+	first := s.answerText("first answer", http.StatusOK)
+	if first.Answer.Text != replies[0] {
+		t.Errorf("answer: got reply %q, want %q", first.Answer.Text, replies[0])
+	}
+
+	last := s.answerText("second answer", http.StatusOK)
+	if last.Answer.Text != replies[1] {
+		t.Errorf("answer: got reply %q, want %q", last.Answer.Text, replies[1])
+	}
+	if last.Progress == nil || !last.Progress.Ended {
+		t.Errorf("answer: got progress %+v, want ended", last.Progress)
+	}
+
+	s.answerText("third answer", http.StatusConflict)
+}
+
+// The interviewer's audio keeps its duration so history and exports do not
+// have to read it back from the blob store.
+func TestAssistantAudioDuration(t *testing.T) {
+	server := newTestServer(t)
+
+	s := startSession(t, server, model.StartChatRequest{
+		Role:     "Backend Engineer",
+		Skills:   []string{"Go"},
+		Language: "en",
+	})
+
+	req, err := http.NewRequest(http.MethodGet, s.server.URL+"/chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var history model.HistoryResponse
+	s.do(req, http.StatusOK, &history)
+
+	if len(history.Turns) != 1 || history.Turns[0].AudioURL == "" {
+		t.Fatalf("got turns %+v, want the spoken greeting", history.Turns)
+	}
+
+	// the fake interviewer speaks for at least a second
+	if history.Turns[0].Duration < 1 {
+		t.Errorf("got duration %v, want at least 1 second", history.Turns[0].Duration)
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/export"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/session"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+// GetExport downloads the interview as a document, e.g. ?format=pdf
+func (h *handler) GetExport(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = export.FORMAT_MARKDOWN
+	}
+
+	if !slices.Contains(export.Formats, format) {
+		log.Printf("unknown export format: %s", format)
+		util.SendResponse(w, nil, "unknown export format", http.StatusBadRequest)
+
+		return
+	}
+
+	entries, err := h.db.GetChatsByChatUserID(user.ID)
+	if err != nil {
+		log.Printf("failed to get chat: %v", err)
+		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
+
+		return
+	}
+
+	content, err := export.Render(format, h.exportSession(req.Context(), user, entries))
+	if err != nil {
+		log.Printf("failed to render export: %v", err)
+		util.SendResponse(w, nil, "failed to export chat", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "interview-" + user.ID + "." + format,
+	}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func (h *handler) exportSession(ctx context.Context, user *data.ChatUser, entries []data.Entry) *export.Session {
+	pack := h.packs.Get(user.Language)
+
+	exported := &export.Session{
+		ID:          user.ID,
+		Role:        user.Role,
+		Skills:      user.Skills,
+		Language:    pack.Name + " (" + pack.Code + ")",
+		Interviewer: h.personas.Resolve(user.Persona).Name,
+		Mode:        user.Mode,
+		Status:      user.Status,
+		EndedAt:     user.EndedAt,
+		Turns:       []export.Turn{},
+	}
+
+	if !user.StartedAt.IsZero() {
+		exported.StartedAt = &user.StartedAt
+	}
+
+	for _, entry := range entries {
+		turn := export.Turn{
+			EntryID:   entry.ID,
+			Seq:       entry.Seq,
+			Text:      entry.Text,
+			CreatedAt: entry.CreatedAt,
+		}
+
+		switch entry.Role {
+		case string(openai.ROLE_USER):
+			turn.Speaker = export.SPEAKER_CANDIDATE
+			turn.RecordingURL = recordingURL(entry)
+			turn.Duration = entry.AudioDuration
+		case string(openai.ROLE_ASSISTANT):
+			turn.Speaker = export.SPEAKER_INTERVIEWER
+			if entry.AudioKey != "" {
+				turn.AudioURL = audioPathPrefix + entry.ID
+				turn.Duration = h.audioDuration(ctx, entry)
+			}
+		default:
+			continue
+		}
+
+		exported.Turns = append(exported.Turns, turn)
+	}
+
+	// the closing feedback is the last word of an interview that ended
+	last := len(exported.Turns) - 1
+	if user.Status == session.STATUS_ENDED && last > 0 && exported.Turns[last].Speaker == export.SPEAKER_INTERVIEWER {
+		exported.Feedback = &exported.Turns[last]
+		exported.Turns = exported.Turns[:last]
+	}
+
+	return exported
+}
+
+// audioDuration is the stored duration of an entry's audio. Entries stored
+// before the duration was kept have their audio measured, 0 when it cannot.
+func (h *handler) audioDuration(ctx context.Context, entry data.Entry) float64 {
+	if entry.AudioDuration > 0 {
+		return entry.AudioDuration
+	}
+
+	object, err := h.blobs.Get(ctx, entry.AudioKey)
+	if err != nil {
+		log.Printf("failed to get audio: %v", err)
+
+		return 0
+	}
+	defer object.Close()
+
+	content, err := io.ReadAll(object)
+	if err != nil {
+		log.Printf("failed to read audio: %v", err)
+
+		return 0
+	}
+
+	return export.Duration(content, object.ContentType)
+}
@@ -1,30 +1,142 @@
 package handler
 
 import (
+	"log"
+	"time"
+
 	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
 
 	"github.com/go-chi/cors"
 
+	"github.com/madeindra/mock-interview/server/internal/blob"
+	"github.com/madeindra/mock-interview/server/internal/budget"
 	"github.com/madeindra/mock-interview/server/internal/config"
 	"github.com/madeindra/mock-interview/server/internal/data"
-	"github.com/madeindra/mock-interview/server/internal/elevenlab"
+	"github.com/madeindra/mock-interview/server/internal/history"
+	"github.com/madeindra/mock-interview/server/internal/language"
 	"github.com/madeindra/mock-interview/server/internal/middleware"
 	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/persona"
+	"github.com/madeindra/mock-interview/server/internal/provider"
+	"github.com/madeindra/mock-interview/server/internal/resilience"
+	"github.com/madeindra/mock-interview/server/internal/rubric"
+	"github.com/madeindra/mock-interview/server/internal/tts"
+	"github.com/madeindra/mock-interview/server/internal/usage"
 )
 
 type handler struct {
-	ai openai.Client
-	el elevenlab.Client
-	db *data.Database
+	ai     openai.Client
+	speech *tts.Router
+	db     data.Store
+
+	history  *history.Manager
+	breakers []*resilience.Breaker
+	upgrader websocket.Upgrader
+	prices   usage.PriceTable
+	budget   *budget.Checker
+	clients  *clientIdentifier
+	personas *persona.Catalog
+	packs    *language.Registry
+	rubric   *rubric.Rubric
+	// sessionTTL is how long after its start a session expires, 0 never
+	sessionTTL time.Duration
+	blobs      blob.Store
+	audioURLs  bool
 }
 
 func NewHandler(cfg config.AppConfig) *chi.Mux {
+	return newHandler(cfg).routes(cfg)
+}
+
+func newHandler(cfg config.AppConfig) *handler {
+	ai, err := provider.NewClient(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	policy := resilience.Policy{
+		MaxAttempts: cfg.RetryMaxAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+	}
+
+	aiBreaker := resilience.NewBreaker("ai", cfg.BreakerThreshold, cfg.BreakerCooldown)
+	ttsBreaker := resilience.NewBreaker("tts", cfg.BreakerThreshold, cfg.BreakerCooldown)
+
+	prices := usage.DefaultPriceTable()
+	if cfg.PriceTablePath != "" {
+		prices, err = usage.LoadPriceTable(cfg.PriceTablePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	personas := persona.DefaultCatalog()
+	if cfg.PersonasPath != "" {
+		personas, err = persona.LoadCatalog(cfg.PersonasPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	packs, err := language.Load(cfg.LanguagePacksPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobs, err := provider.NewBlobStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	clients, err := newClientIdentifier(cfg.ClientAPIKeys, cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	answerRubric := rubric.DefaultRubric()
+	if cfg.RubricPath != "" {
+		answerRubric, err = rubric.Load(cfg.RubricPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	h := &handler{
-		ai: openai.NewOpenAI(cfg.APIKey),
-		el: elevenlab.NewElevenLab(cfg.TTSAPIKey),
+		ai: resilience.NewClient(ai, policy, aiBreaker),
 		db: data.New(cfg.DBPath),
+
+		breakers: []*resilience.Breaker{aiBreaker, ttsBreaker},
+		upgrader: newUpgrader(cfg.CORSOrigins),
+		prices:   prices,
+		clients:  clients,
+		personas: personas,
+		packs:    packs,
+		rubric:   answerRubric,
+
+		sessionTTL: cfg.SessionTTL,
+		blobs:      blobs,
+		audioURLs:  cfg.AudioURLs,
 	}
 
+	h.speech, err = provider.NewSpeechRouter(cfg, h.ai, resilience.NewTTSClient(provider.NewTTSClient(cfg), policy, ttsBreaker), packs.Routes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	h.history = history.NewManager(h.ai, h.db, cfg.HistoryMaxTokens, cfg.HistoryRecentMessages)
+	h.budget = budget.NewChecker(h.db, budget.Limits{
+		MaxTurns:        cfg.BudgetMaxTurns,
+		MaxAudioSeconds: cfg.BudgetMaxAudioSeconds,
+		MaxTokens:       cfg.BudgetMaxTokens,
+		MaxDailySpend:   cfg.BudgetDailySpend,
+	}, prices)
+
+	return h
+}
+
+func (h *handler) routes(cfg config.AppConfig) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(cors.Handler(cors.Options{
@@ -32,14 +144,33 @@ func NewHandler(cfg config.AppConfig) *chi.Mux {
 		AllowedMethods: cfg.CORSMethods,
 		AllowedHeaders: cfg.CORSHeaders,
 	}))
+	r.Use(middleware.TrackUsage)
 
 	r.Get("/chat/status", h.Status)
 	r.Post("/chat/start", h.StartChat)
+	r.Get("/personas", h.GetPersonas)
+	r.Get("/languages", h.GetLanguages)
 
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.BasicAuth)
+		r.Get("/chat", h.GetHistory)
 		r.Post("/chat/answer", h.AnswerChat)
+		r.Post("/chat/answer/stream", h.AnswerChatStream)
+		r.Get("/chat/realtime", h.RealtimeChat)
 		r.Get("/chat/end", h.EndChat)
+		r.Get("/chat/usage", h.GetUsage)
+		r.Get("/chat/report", h.GetReport)
+		r.Get("/chat/audio/{entryID}", h.GetAudio)
+		r.Get("/chat/recording/{entryID}", h.GetRecording)
+		r.Get("/chat/export", h.GetExport)
+		r.Get("/chat/session", h.GetSession)
+		r.Post("/chat/pause", h.PauseChat)
+		r.Post("/chat/resume", h.ResumeChat)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AdminAuth(cfg.AdminAPIKey))
+		r.Get("/admin/usage", h.GetAdminUsage)
 	})
 
 	return r
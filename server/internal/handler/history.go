@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/rubric"
+	"github.com/madeindra/mock-interview/server/internal/session"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// GetHistory returns the turns of the interview so a client can restore
+// it, a page at a time with ?offset= and ?limit=
+func (h *handler) GetHistory(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+
+	offset, err := queryInt(req, "offset", 0)
+	if err != nil || offset < 0 {
+		log.Printf("invalid offset: %v", req.URL.Query().Get("offset"))
+		util.SendResponse(w, nil, "offset must be a non-negative number", http.StatusBadRequest)
+
+		return
+	}
+
+	limit, err := queryInt(req, "limit", defaultHistoryLimit)
+	if err != nil || limit < 1 || limit > maxHistoryLimit {
+		log.Printf("invalid limit: %v", req.URL.Query().Get("limit"))
+		util.SendResponse(w, nil, "limit must be between 1 and "+strconv.Itoa(maxHistoryLimit), http.StatusBadRequest)
+
+		return
+	}
+
+	entries, err := h.db.GetConversation(user.ID, offset, limit)
+	if err != nil {
+		log.Printf("failed to get chat: %v", err)
+		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
+
+		return
+	}
+
+	total, err := h.db.CountConversation(user.ID)
+	if err != nil {
+		log.Printf("failed to count chat: %v", err)
+		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
+
+		return
+	}
+
+	showScores := user.Mode == model.MODE_PRACTICE || user.Status == session.STATUS_ENDED || user.Status == session.STATUS_EXPIRED
+
+	turns := []model.Turn{}
+	for _, entry := range entries {
+		turns = append(turns, historyTurn(entry, showScores))
+	}
+
+	response := model.HistoryResponse{
+		Language: h.packs.Get(user.Language).Code,
+		Status:   user.Status,
+		Turns:    turns,
+		Total:    total,
+		Offset:   offset,
+		Limit:    limit,
+	}
+
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
+
+func historyTurn(entry data.Entry, showScore bool) model.Turn {
+	turn := model.Turn{
+		EntryID:   entry.ID,
+		Seq:       entry.Seq,
+		Role:      entry.Role,
+		CreatedAt: entry.CreatedAt,
+		Chat:      model.Chat{Text: entry.Text},
+	}
+
+	if entry.Role == string(openai.ROLE_USER) {
+		turn.RecordingURL = recordingURL(entry)
+		turn.Duration = entry.AudioDuration
+	} else if entry.AudioKey != "" {
+		turn.AudioURL = audioPathPrefix + entry.ID
+		turn.Duration = entry.AudioDuration
+	}
+
+	if showScore && entry.Score != "" {
+		var score rubric.Score
+		if err := json.Unmarshal([]byte(entry.Score), &score); err != nil {
+			log.Printf("failed to parse score of %s: %v", entry.ID, err)
+		} else {
+			turn.Score = &score
+		}
+	}
+
+	return turn
+}
+
+// queryInt parses a query parameter, fallback when it is not set.
+func queryInt(req *http.Request, name string, fallback int) (int, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+func (h *handler) GetLanguages(w http.ResponseWriter, req *http.Request) {
+	packs := h.packs.List()
+
+	response := make([]model.LanguageResponse, len(packs))
+	for i, pack := range packs {
+		response[i] = model.LanguageResponse{
+			Code: pack.Code,
+			Name: pack.Name,
+		}
+	}
+
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
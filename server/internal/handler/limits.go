@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/session"
+)
+
+// progress measures the session against its limits as if it had that many
+// answers, the clock stops when the session ends.
+func progress(user *data.ChatUser, answers int) session.Progress {
+	now := time.Now()
+	if user.EndedAt != nil {
+		now = *user.EndedAt
+	}
+
+	limits := session.Limits{Duration: user.Duration, MaxQuestions: user.MaxQuestions}
+	elapsed := session.Elapsed(user.StartedAt, user.Paused, user.PausedAt, now)
+
+	return limits.Measure(elapsed, answers)
+}
+
+// turnMessages appends the candidate's answer to the history. Once a limit
+// is hit the interviewer is asked for the closing feedback instead of
+// another question, otherwise it is told how much of the interview is left.
+func turnMessages(history []openai.ChatMessage, pack *language.Pack, progress session.Progress, answer string) ([]openai.ChatMessage, error) {
+	messages := append(history, openai.ChatMessage{
+		Role:    openai.ROLE_USER,
+		Content: answer,
+	})
+
+	if progress.Done() {
+		endPrompt, err := pack.RenderEndPrompt(language.Sections)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(messages, openai.ChatMessage{
+			Role:    openai.ROLE_USER,
+			Content: endPrompt,
+		}), nil
+	}
+
+	prompt, err := pack.RenderProgressPrompt(progress.Elapsed, progress.Remaining, progress.Answers, progress.RemainingQuestions)
+	if err != nil {
+		return nil, err
+	}
+
+	if prompt != "" {
+		messages = append(messages, openai.ChatMessage{
+			Role:    openai.ROLE_SYSTEM,
+			Content: prompt,
+		})
+	}
+
+	return messages, nil
+}
+
+// progressResponse is nil for sessions without limits.
+func progressResponse(progress session.Progress) *model.Progress {
+	if progress.Remaining == nil && progress.RemainingQuestions == nil {
+		return nil
+	}
+
+	response := &model.Progress{
+		ElapsedSeconds:     int(progress.Elapsed.Seconds()),
+		RemainingQuestions: progress.RemainingQuestions,
+		Ended:              progress.Done(),
+		OverTime:           progress.Remaining != nil && *progress.Remaining == 0,
+	}
+
+	if progress.Remaining != nil {
+		response.RemainingSeconds = new(int)
+		*response.RemainingSeconds = int(progress.Remaining.Seconds())
+	}
+
+	return response
+}
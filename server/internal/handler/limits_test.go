@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/fake"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/session"
+)
+
+// agedStore makes sessions look as if they started age earlier.
+type agedStore struct {
+	data.Store
+	age time.Duration
+}
+
+func (s *agedStore) GetChatUser(id string) (*data.ChatUser, error) {
+	user, err := s.Store.GetChatUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.StartedAt = user.StartedAt.Add(-s.age)
+
+	return user, nil
+}
+
+func (s *testSession) session() model.SessionResponse {
+	s.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, s.server.URL+"/chat/session", nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	var response model.SessionResponse
+	s.do(req, http.StatusOK, &response)
+
+	return response
+}
+
+// startOverTime starts a one minute interview, answers once and lets its
+// time run out.
+func startOverTime(t *testing.T) *testSession {
+	t.Helper()
+
+	store := &agedStore{}
+	server := newTestServerWithStore(t, func(db data.Store) data.Store {
+		store.Store = db
+		return store
+	})
+
+	s := startSession(t, server, model.StartChatRequest{
+		Role:            "Backend Engineer",
+		Skills:          []string{"Go"},
+		DurationMinutes: 1,
+	})
+
+	s.answerText("first answer", http.StatusOK)
+
+	store.age = 2 * time.Minute
+
+	// running out of time leaves the session to be closed with feedback
+	current := s.session()
+	if current.Status != session.STATUS_ACTIVE {
+		t.Fatalf("got status %q once over time, want %q", current.Status, session.STATUS_ACTIVE)
+	}
+	if current.Progress == nil || !current.Progress.OverTime {
+		t.Fatalf("got progress %+v, want over time", current.Progress)
+	}
+
+	return s
+}
+
+func TestTimeLimitThenAnswer(t *testing.T) {
+	replies := fake.DefaultScript().Replies
+	s := startOverTime(t)
+
+	last := s.answerText("second answer", http.StatusOK)
+	if last.Answer.Text != replies[1] {
+		t.Errorf("answer: got feedback %q, want %q", last.Answer.Text, replies[1])
+	}
+	if last.Progress == nil || !last.Progress.Ended {
+		t.Errorf("answer: got progress %+v, want ended", last.Progress)
+	}
+
+	if status := s.session().Status; status != session.STATUS_ENDED {
+		t.Errorf("got status %q after the feedback, want %q", status, session.STATUS_ENDED)
+	}
+
+	s.answerText("third answer", http.StatusConflict)
+	s.end(http.StatusConflict)
+}
+
+func TestTimeLimitThenEnd(t *testing.T) {
+	replies := fake.DefaultScript().Replies
+	s := startOverTime(t)
+
+	end := s.end(http.StatusOK)
+	if end.Answer.Text != replies[1] {
+		t.Errorf("end: got feedback %q, want %q", end.Answer.Text, replies[1])
+	}
+
+	if status := s.session().Status; status != session.STATUS_ENDED {
+		t.Errorf("got status %q after the feedback, want %q", status, session.STATUS_ENDED)
+	}
+
+	s.answerText("second answer", http.StatusConflict)
+}
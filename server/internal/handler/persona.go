@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+func (h *handler) GetPersonas(w http.ResponseWriter, req *http.Request) {
+	personas := h.personas.List()
+
+	response := make([]model.PersonaResponse, len(personas))
+	for i, persona := range personas {
+		response[i] = model.PersonaResponse{
+			ID:          persona.ID,
+			Name:        persona.Name,
+			Gender:      persona.Gender,
+			Description: persona.Description,
+		}
+	}
+
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
+
+// voices returns the speech voices of the session's interviewer.
+func (h *handler) voices(user *data.ChatUser) map[string]string {
+	return h.personas.Resolve(user.Persona).Voices
+}
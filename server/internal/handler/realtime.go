@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/middleware"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/session"
+	"github.com/madeindra/mock-interview/server/internal/util"
+	"github.com/madeindra/mock-interview/server/internal/vad"
+)
+
+const (
+	defaultSampleRate     = 16000
+	maxRealtimeFrame      = 1 << 20
+	realtimeAudioChunk    = 16 << 10
+	realtimeAudioFilename = "answer.wav"
+
+	// the client must send something, at least a pong, within realtimePongWait
+	realtimePongWait   = 60 * time.Second
+	realtimePingPeriod = realtimePongWait * 9 / 10
+	realtimeWriteWait  = 10 * time.Second
+)
+
+// realtimeSession is a single WebSocket interview. The candidate streams
+// 16-bit mono PCM as binary frames, the server detects the end of each
+// answer and replies with JSON events followed by the interviewer's audio
+// as binary frames.
+type realtimeSession struct {
+	h    *handler
+	conn *websocket.Conn
+	user *data.ChatUser
+
+	// ctx is cancelled once the client disconnects, aborting the turn
+	ctx context.Context
+
+	detector *vad.Detector
+	busy     atomic.Bool
+	turns    sync.WaitGroup
+	writeMu  sync.Mutex
+}
+
+func newUpgrader(origins []string) websocket.Upgrader {
+	return websocket.Upgrader{
+		Subprotocols: []string{middleware.WebSocketProtocol},
+		CheckOrigin: func(req *http.Request) bool {
+			origin := req.Header.Get("Origin")
+			for _, allowed := range origins {
+				if allowed == "*" || allowed == origin {
+					return true
+				}
+			}
+
+			return origin == ""
+		},
+	}
+}
+
+func (h *handler) RealtimeChat(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+
+	if err := session.Active(user.Status); err != nil {
+		sendSessionError(w, h.packs.Get(user.Language), nil, err)
+
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Printf("failed to upgrade connection: %v", err)
+
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(maxRealtimeFrame)
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	conversation := &realtimeSession{
+		h:        h,
+		conn:     conn,
+		user:     user,
+		ctx:      ctx,
+		detector: vad.NewDetector(defaultSampleRate),
+	}
+
+	conversation.send(model.RealtimeMessage{Type: model.EVENT_READY, Language: h.packs.Get(user.Language).Code})
+	go conversation.ping()
+	conversation.listen()
+
+	cancel()
+	conversation.turns.Wait()
+}
+
+// ping keeps the connection alive until the session is over, a client that
+// stops answering is dropped once the read deadline passes.
+func (s *realtimeSession) ping() {
+	ticker := time.NewTicker(realtimePingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(realtimeWriteWait)); err != nil {
+				log.Printf("failed to ping realtime client: %v", err)
+
+				return
+			}
+		}
+	}
+}
+
+func (s *realtimeSession) listen() {
+	s.conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	})
+
+	for {
+		messageType, payload, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("failed to read realtime message: %v", err)
+			}
+
+			return
+		}
+
+		s.conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+
+		switch messageType {
+		case websocket.BinaryMessage:
+			s.handleAudio(payload)
+		case websocket.TextMessage:
+			s.handleMessage(payload)
+		}
+	}
+}
+
+// handleAudio feeds the detector, audio that arrives while the interviewer
+// is answering is dropped.
+func (s *realtimeSession) handleAudio(pcm []byte) {
+	if s.busy.Load() {
+		return
+	}
+
+	wasSpeaking := s.detector.Speaking()
+	done := s.detector.Write(pcm)
+
+	if !wasSpeaking && s.detector.Speaking() {
+		s.send(model.RealtimeMessage{Type: model.EVENT_SPEECH_STARTED})
+	}
+
+	if done {
+		s.startTurn(s.detector.Utterance())
+	}
+}
+
+func (s *realtimeSession) handleMessage(payload []byte) {
+	var message model.RealtimeMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		s.sendError("invalid message")
+
+		return
+	}
+
+	switch message.Type {
+	case model.MESSAGE_CONFIG:
+		if s.busy.Load() || !vad.ValidSampleRate(message.SampleRate) {
+			s.sendError("invalid config")
+
+			return
+		}
+
+		s.detector = vad.NewDetector(message.SampleRate)
+	case model.MESSAGE_COMMIT:
+		if s.busy.Load() || !s.detector.Speaking() {
+			return
+		}
+
+		s.startTurn(s.detector.Utterance())
+	default:
+		s.sendError("unknown message type")
+	}
+}
+
+func (s *realtimeSession) startTurn(wav []byte) {
+	s.busy.Store(true)
+	s.turns.Add(1)
+
+	go func() {
+		defer s.turns.Done()
+		defer s.busy.Store(false)
+
+		s.processTurn(wav)
+	}()
+}
+
+func (s *realtimeSession) processTurn(wav []byte) {
+	h := s.h
+	// turns never overlap, so each one drains only its own usage
+	defer h.saveUsage(s.ctx, s.user.ID, s.user.Client)
+
+	// the session can be paused or ended by another request meanwhile, so
+	// the record is read once per turn and used for the whole turn
+	user, err := h.db.GetChatUser(s.user.ID)
+	if err != nil {
+		log.Printf("failed to get chat user: %v", err)
+		s.sendError("failed to get chat user")
+
+		return
+	}
+
+	if err := h.expire(s.ctx, user); err != nil {
+		log.Printf("failed to expire session: %v", err)
+		s.sendError("failed to get chat user")
+
+		return
+	}
+
+	userAnswer := &answer{recording: wav, contentType: "audio/wav", filename: realtimeAudioFilename}
+
+	response, turnErr := h.answerTurn(s.ctx, user, userAnswer, turnEvents{
+		transcript: func(text string) error {
+			return s.send(model.RealtimeMessage{Type: model.EVENT_TRANSCRIPT, Text: text})
+		},
+		token: func(delta string) error {
+			return s.send(model.RealtimeMessage{Type: model.EVENT_TOKEN, Text: delta})
+		},
+		answer: func(text string) error {
+			return s.send(model.RealtimeMessage{Type: model.EVENT_ANSWER, Text: text})
+		},
+		speech: s.streamSpeech,
+		audio: func(_, ssml string) error {
+			return s.send(model.RealtimeMessage{Type: model.EVENT_AUDIO_END, SSML: ssml})
+		},
+	})
+	if turnErr != nil {
+		if !turnErr.dropped {
+			s.sendTurnError(turnErr)
+		}
+
+		return
+	}
+
+	s.send(model.RealtimeMessage{Type: model.EVENT_DONE, Score: response.Score, Progress: response.Progress})
+}
+
+// streamSpeech forwards the synthesized audio to the client in chunks as it
+// is read from the speech engine, and returns it base64 encoded for storage.
+func (s *realtimeSession) streamSpeech(ctx context.Context, user *data.ChatUser, text string) (string, error) {
+	speech, err := util.SynthesizeSpeech(ctx, s.h.speech, user.Language, text, s.h.voices(user))
+	if err != nil {
+		return "", err
+	}
+
+	if speech == nil {
+		return "", nil
+	}
+	defer speech.Close()
+
+	var audio bytes.Buffer
+	chunk := make([]byte, realtimeAudioChunk)
+
+	for {
+		n, err := speech.Read(chunk)
+		if n > 0 {
+			audio.Write(chunk[:n])
+
+			if err := s.write(websocket.BinaryMessage, chunk[:n]); err != nil {
+				return "", err
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(audio.Bytes()), nil
+}
+
+func (s *realtimeSession) send(message model.RealtimeMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return s.write(websocket.TextMessage, payload)
+}
+
+func (s *realtimeSession) sendError(message string) {
+	if err := s.send(model.RealtimeMessage{Type: model.EVENT_ERROR, Message: message}); err != nil {
+		log.Printf("failed to send error message: %v", err)
+	}
+}
+
+func (s *realtimeSession) sendTurnError(turnErr *turnError) {
+	code, message := turnErr.describe(s.h.packs.Get(s.user.Language))
+	if err := s.send(model.RealtimeMessage{Type: model.EVENT_ERROR, Message: message, Code: code}); err != nil {
+		log.Printf("failed to send error message: %v", err)
+	}
+}
+
+func (s *realtimeSession) write(messageType int, payload []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+
+	return s.conn.WriteMessage(messageType, payload)
+}
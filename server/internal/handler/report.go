@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/madeindra/mock-interview/server/internal/budget"
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/report"
+	"github.com/madeindra/mock-interview/server/internal/session"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+var errNoAnswers = errors.New("the interview has no answers to evaluate")
+
+// GetReport returns the evaluation stored when the interview ended. Sessions
+// whose report could not be generated then get it generated on request.
+func (h *handler) GetReport(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+	defer h.saveUsage(req.Context(), user.ID, user.Client)
+
+	pack := h.packs.Get(user.Language)
+
+	if user.Status == session.STATUS_ACTIVE || user.Status == session.STATUS_PAUSED {
+		log.Println("chat is still running")
+		util.SendResponse(w, nil, "the report is available once the interview has ended", http.StatusConflict)
+
+		return
+	}
+
+	stored, err := h.db.GetReport(user.ID)
+	if err != nil && !errors.Is(err, data.ErrNotFound) {
+		log.Printf("failed to get report: %v", err)
+		util.SendResponse(w, nil, "failed to get report", http.StatusInternalServerError)
+
+		return
+	}
+
+	if stored != nil {
+		var evaluation report.Report
+		if err := json.Unmarshal([]byte(stored.Content), &evaluation); err != nil {
+			log.Printf("failed to parse report: %v", err)
+			util.SendResponse(w, nil, "failed to get report", http.StatusInternalServerError)
+
+			return
+		}
+
+		util.SendResponse(w, model.ReportResponse{Report: evaluation, CreatedAt: stored.CreatedAt}, "success", http.StatusOK)
+
+		return
+	}
+
+	saved, evaluation, err := h.createReport(req.Context(), user)
+	if errors.Is(err, errNoAnswers) {
+		log.Println("chat has no answers to evaluate")
+		util.SendResponse(w, nil, "the interview has no answers to evaluate", http.StatusConflict)
+
+		return
+	}
+
+	var budgetErr *budget.Error
+	if errors.As(err, &budgetErr) {
+		sendBudgetError(w, pack, nil, err)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("failed to generate report: %v", err)
+		util.SendResponse(w, nil, "failed to generate report", http.StatusInternalServerError)
+
+		return
+	}
+
+	util.SendResponse(w, model.ReportResponse{Report: *evaluation, CreatedAt: saved.CreatedAt}, "success", http.StatusOK)
+}
+
+// createReport evaluates the interview and stores the report.
+func (h *handler) createReport(ctx context.Context, user *data.ChatUser) (*data.Report, *report.Report, error) {
+	entries, err := h.db.GetChatsByChatUserID(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if countAnswers(entries) == 0 {
+		return nil, nil, errNoAnswers
+	}
+
+	if err := h.budget.CheckSession(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	evaluation, err := report.Generate(ctx, h.ai, h.packs.Get(user.Language), user.Role, user.Skills, entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := json.Marshal(evaluation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	saved, err := h.db.SaveReport(user.ID, string(content), len(entries))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return saved, evaluation, nil
+}
+
+// endReport stores the report of a session that has just ended. A failure
+// does not fail the request, the report is then generated on request.
+func (h *handler) endReport(ctx context.Context, user *data.ChatUser) {
+	if _, _, err := h.createReport(ctx, user); err != nil && !errors.Is(err, errNoAnswers) {
+		log.Printf("failed to generate report: %v", err)
+	}
+}
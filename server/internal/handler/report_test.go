@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/madeindra/mock-interview/server/internal/model"
+)
+
+func (s *testSession) report(wantStatus int) model.ReportResponse {
+	s.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, s.server.URL+"/chat/report", nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	var report model.ReportResponse
+	s.do(req, wantStatus, &report)
+
+	return report
+}
+
+func TestReportScoresEverySkill(t *testing.T) {
+	server := newTestServer(t)
+
+	s := startSession(t, server, model.StartChatRequest{
+		Role:     "Backend Engineer",
+		Skills:   []string{"Go", "System Design", "Go"},
+		Language: "en",
+	})
+
+	s.answerText("I built a payment service in Go.", http.StatusOK)
+	s.report(http.StatusConflict)
+	s.end(http.StatusOK)
+
+	report := s.report(http.StatusOK)
+
+	var skills []string
+	for _, score := range report.Skills {
+		skills = append(skills, score.Skill)
+	}
+
+	if len(skills) != 2 || skills[0] != "Go" || skills[1] != "System Design" {
+		t.Errorf("got scores for %v, want [Go System Design]", skills)
+	}
+
+	if again := s.report(http.StatusOK); !again.CreatedAt.Equal(report.CreatedAt) {
+		t.Errorf("got a report created at %v, want the stored one from %v", again.CreatedAt, report.CreatedAt)
+	}
+}
+
+func TestReportAfterQuestionLimit(t *testing.T) {
+	server := newTestServer(t)
+
+	s := startSession(t, server, model.StartChatRequest{
+		Role:         "Backend Engineer",
+		Skills:       []string{"Go"},
+		Language:     "en",
+		MaxQuestions: 1,
+	})
+
+	s.answerText("I built a payment service in Go.", http.StatusOK)
+
+	if report := s.report(http.StatusOK); len(report.Skills) != 1 {
+		t.Errorf("got %d skill scores, want 1", len(report.Skills))
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/rubric"
+)
+
+// scoreAnswer scores the answer to the last question when the session asked
+// for it, returning the score as stored with the entry and as shown to the
+// candidate. A failed evaluation only loses the score, never the turn.
+func (h *handler) scoreAnswer(ctx context.Context, user *data.ChatUser, pack *language.Pack, entries []data.Entry, answer string) (string, *rubric.Score) {
+	if !user.Scoring {
+		return "", nil
+	}
+
+	var question string
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Role == string(openai.ROLE_ASSISTANT) {
+			question = entries[i].Text
+			break
+		}
+	}
+
+	score, err := h.rubric.Score(ctx, h.ai, pack, question, answer)
+	if err != nil {
+		log.Printf("failed to score answer: %v", err)
+		return "", nil
+	}
+
+	content, err := json.Marshal(score)
+	if err != nil {
+		log.Printf("failed to encode score: %v", err)
+		return "", nil
+	}
+
+	if user.Mode != model.MODE_PRACTICE {
+		return string(content), nil
+	}
+
+	return string(content), score
+}
+
+// answerScores collects the stored score of every answer.
+func answerScores(entries []data.Entry) []model.AnswerScore {
+	var scores []model.AnswerScore
+	for _, entry := range entries {
+		if entry.Score == "" {
+			continue
+		}
+
+		var score rubric.Score
+		if err := json.Unmarshal([]byte(entry.Score), &score); err != nil {
+			log.Printf("failed to parse score of %s: %v", entry.ID, err)
+			continue
+		}
+
+		scores = append(scores, model.AnswerScore{EntryID: entry.ID, Text: entry.Text, Score: score})
+	}
+
+	return scores
+}
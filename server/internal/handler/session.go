@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/session"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+func (h *handler) GetSession(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+
+	entries, err := h.db.GetChatsByChatUserID(user.ID)
+	if err != nil {
+		log.Printf("failed to get chat: %v", err)
+		util.SendResponse(w, nil, "failed to get chat", http.StatusInternalServerError)
+
+		return
+	}
+
+	response := sessionResponse(user)
+	response.Progress = progressResponse(progress(user, countAnswers(entries)))
+
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
+
+func (h *handler) PauseChat(w http.ResponseWriter, req *http.Request) {
+	h.transition(w, req, session.STATUS_PAUSED)
+}
+
+func (h *handler) ResumeChat(w http.ResponseWriter, req *http.Request) {
+	h.transition(w, req, session.STATUS_ACTIVE)
+}
+
+// transition moves the session to status, answering 409 when it cannot get
+// there from where it is.
+func (h *handler) transition(w http.ResponseWriter, req *http.Request, status string) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+
+	pack := h.packs.Get(user.Language)
+
+	if err := session.Transition(user.Status, status); err != nil {
+		sendSessionError(w, pack, nil, err)
+
+		return
+	}
+
+	tx, err := h.db.BeginTx()
+	if err != nil {
+		log.Printf("failed to begin transaction: %v", err)
+		util.SendResponse(w, nil, "failed to update session", http.StatusInternalServerError)
+
+		return
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.UpdateChatUserStatus(user.ID, status)
+	if err != nil {
+		log.Printf("failed to update session status: %v", err)
+		util.SendResponse(w, nil, "failed to update session", http.StatusInternalServerError)
+
+		return
+	}
+
+	// another request changed the status since it was read, report the
+	// status it left the session in
+	if !updated {
+		tx.Rollback()
+
+		current, err := h.db.GetChatUser(user.ID)
+		if err != nil {
+			log.Printf("failed to get chat user: %v", err)
+			util.SendResponse(w, nil, "failed to update session", http.StatusInternalServerError)
+
+			return
+		}
+
+		sendSessionError(w, pack, nil, session.StatusError(current.Status))
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("failed to commit transaction: %v", err)
+		util.SendResponse(w, nil, "failed to update session", http.StatusInternalServerError)
+
+		return
+	}
+
+	user.Status = status
+
+	util.SendResponse(w, sessionResponse(user), "success", http.StatusOK)
+}
+
+// expire ends a session that has outlived its TTL, as expired, and stores
+// its report. One that used up its time limit is left over time instead, so
+// the next answer or end gets the closing feedback.
+func (h *handler) expire(ctx context.Context, user *data.ChatUser) error {
+	if user.Status != session.STATUS_ACTIVE && user.Status != session.STATUS_PAUSED {
+		return nil
+	}
+
+	now := time.Now()
+	if !session.Expired(user.StartedAt, h.sessionTTL, now) {
+		return nil
+	}
+
+	tx, err := h.db.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.UpdateChatUserStatus(user.ID, session.STATUS_EXPIRED)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// another request ended the session first
+	if !updated {
+		current, err := h.db.GetChatUser(user.ID)
+		if err != nil {
+			return err
+		}
+
+		*user = *current
+
+		return nil
+	}
+
+	endedAt := now.UTC().Truncate(time.Second)
+	user.Status = session.STATUS_EXPIRED
+	user.EndedAt = &endedAt
+
+	// the request that noticed the expiry may not save usage itself
+	defer h.saveUsage(ctx, user.ID, user.Client)
+	h.endReport(ctx, user)
+
+	return nil
+}
+
+// sessionError returns the code and message, in the session's language, to
+// report for a request the session's status does not allow.
+func sessionError(err error, pack *language.Pack) (string, string) {
+	var sessionErr *session.Error
+	if errors.As(err, &sessionErr) {
+		return sessionErr.Code, pack.Message(sessionErr.Code, sessionErr.Error())
+	}
+
+	return "", err.Error()
+}
+
+func sendSessionError(w http.ResponseWriter, pack *language.Pack, data any, err error) {
+	code, message := sessionError(err, pack)
+	util.SendError(w, data, code, message, http.StatusConflict)
+}
+
+func sessionResponse(user *data.ChatUser) model.SessionResponse {
+	return model.SessionResponse{
+		Status:    user.Status,
+		StartedAt: user.StartedAt,
+		EndedAt:   user.EndedAt,
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+// AnswerChatStream works like AnswerChat but reports its progress as
+// Server-Sent Events: the transcript, every completion token, the full
+// answer, the synthesized audio, and finally the same payload AnswerChat
+// returns. The turn is only saved once everything has been generated.
+func (h *handler) AnswerChatStream(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+	defer h.saveUsage(req.Context(), user.ID, user.Client)
+
+	pack := h.packs.Get(user.Language)
+
+	userAnswer, err := readAnswer(req)
+	if err != nil {
+		log.Printf("failed to read answer: %v", err)
+		util.SendResponse(w, nil, "an audio file or a text answer is required", http.StatusBadRequest)
+
+		return
+	}
+
+	// until the turn is accepted errors are sent with a status, the stream
+	// only starts once the budgets have been checked
+	var flusher http.Flusher
+	send := func(event string, payload any) error {
+		return util.SendEvent(w, flusher, event, payload)
+	}
+
+	response, turnErr := h.answerTurn(req.Context(), user, userAnswer, turnEvents{
+		start: func() error {
+			flusher, err = util.StartEventStream(w)
+			if err != nil {
+				util.SendResponse(w, nil, "streaming is not supported", http.StatusInternalServerError)
+			}
+
+			return err
+		},
+		transcript: func(text string) error {
+			return send(model.EVENT_TRANSCRIPT, model.Chat{Text: text})
+		},
+		token: func(delta string) error {
+			return send(model.EVENT_TOKEN, model.Chat{Text: delta})
+		},
+		answer: func(text string) error {
+			return send(model.EVENT_ANSWER, model.Chat{Text: text})
+		},
+		audio: func(audio, ssml string) error {
+			return send(model.EVENT_AUDIO, model.Chat{Audio: audio, SSML: ssml})
+		},
+	})
+	if turnErr != nil {
+		if flusher == nil {
+			sendTurnError(w, pack, turnErr)
+
+			return
+		}
+
+		if turnErr.dropped {
+			return
+		}
+
+		code, message := turnErr.describe(pack)
+		if err := send(model.EVENT_ERROR, model.Response{Message: message, Code: code}); err != nil {
+			log.Printf("failed to send error event: %v", err)
+		}
+
+		return
+	}
+
+	if err := send(model.EVENT_DONE, response); err != nil {
+		log.Printf("failed to send done event: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/madeindra/mock-interview/server/internal/fake"
+	"github.com/madeindra/mock-interview/server/internal/model"
+)
+
+type streamEvent struct {
+	name string
+	data string
+}
+
+// answerStream answers over Server-Sent Events and returns the events sent.
+func (s *testSession) answerStream(text string) []streamEvent {
+	s.t.Helper()
+
+	body, err := json.Marshal(model.AnswerChatRequest{Text: text, TextOnly: true})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/chat/answer/stream", bytes.NewReader(body))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(s.id, s.secret)
+
+	resp, err := s.server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.t.Fatalf("stream: got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var events []streamEvent
+	var event streamEvent
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, event)
+			event = streamEvent{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		s.t.Fatalf("stream: %v", err)
+	}
+
+	return events
+}
+
+func TestAnswerChatStream(t *testing.T) {
+	server := newTestServer(t)
+	replies := fake.DefaultScript().Replies
+
+	s := startSession(t, server, model.StartChatRequest{Role: "Backend Engineer", Skills: []string{"Go"}})
+
+	events := s.answerStream("I built a payment service in Go.")
+	if len(events) < 3 {
+		t.Fatalf("got %d events, want at least a transcript, an answer and done", len(events))
+	}
+
+	if events[0].name != model.EVENT_TRANSCRIPT {
+		t.Errorf("got first event %q, want %q", events[0].name, model.EVENT_TRANSCRIPT)
+	}
+
+	var tokens strings.Builder
+	for _, event := range events[1 : len(events)-2] {
+		if event.name != model.EVENT_TOKEN {
+			t.Fatalf("got event %q between the transcript and the answer, want %q", event.name, model.EVENT_TOKEN)
+		}
+
+		var chat model.Chat
+		if err := json.Unmarshal([]byte(event.data), &chat); err != nil {
+			t.Fatal(err)
+		}
+		tokens.WriteString(chat.Text)
+	}
+
+	if tokens.String() != replies[0] {
+		t.Errorf("got tokens %q, want %q", tokens.String(), replies[0])
+	}
+
+	// text-only answers have no audio event
+	if name := events[len(events)-2].name; name != model.EVENT_ANSWER {
+		t.Errorf("got event %q before done, want %q", name, model.EVENT_ANSWER)
+	}
+
+	done := events[len(events)-1]
+	if done.name != model.EVENT_DONE {
+		t.Fatalf("got last event %q, want %q", done.name, model.EVENT_DONE)
+	}
+
+	var response model.AnswerChatResponse
+	if err := json.Unmarshal([]byte(done.data), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Prompt.Text != "I built a payment service in Go." || response.Answer.Text != replies[0] {
+		t.Errorf("got done %q / %q, want the answer and the reply", response.Prompt.Text, response.Answer.Text)
+	}
+
+	// the turn was saved, so the next answer gets the next reply
+	if next := s.answerText("I would shard by customer.", http.StatusOK); next.Answer.Text != replies[1] {
+		t.Errorf("got reply %q, want %q", next.Answer.Text, replies[1])
+	}
+}
+
+// A turn the session does not allow is refused with a status, before the
+// stream starts.
+func TestAnswerChatStreamEnded(t *testing.T) {
+	server := newTestServer(t)
+
+	s := startSession(t, server, model.StartChatRequest{Role: "Backend Engineer", Skills: []string{"Go"}})
+	s.end(http.StatusOK)
+
+	body, err := json.Marshal(model.AnswerChatRequest{Text: "one more thing", TextOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/chat/answer/stream", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	s.do(req, http.StatusConflict, nil)
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/export"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/session"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+// turnEvents adapts a turn to how it is delivered. Every callback is
+// optional, and one that fails aborts the turn.
+type turnEvents struct {
+	// start is called once the turn is accepted, before anything is paid for
+	start      func() error
+	transcript func(text string) error
+	// token streams the completion, which is generated in one piece without it
+	token  func(delta string) error
+	answer func(text string) error
+	// speech replaces the synthesis of the reply, e.g. to stream it as it is
+	// generated
+	speech func(ctx context.Context, user *data.ChatUser, text string) (string, error)
+	// audio is called unless the answer asked for text only
+	audio func(audio, ssml string) error
+}
+
+// turnError is a failed turn. Budget and session errors are reported with
+// their code, anything else with message and status.
+type turnError struct {
+	budget  error
+	session error
+	message string
+	status  int
+
+	// transcript of the answer, when it got that far, so it is not lost
+	transcript string
+	// dropped turns have been reported already, or the client is gone
+	dropped bool
+}
+
+// describe returns the code and message, in the session's language, to
+// report the error with.
+func (e *turnError) describe(pack *language.Pack) (string, string) {
+	switch {
+	case e.budget != nil:
+		return budgetError(e.budget, pack)
+	case e.session != nil:
+		return sessionError(e.session, pack)
+	}
+
+	return "", e.message
+}
+
+func sendTurnError(w http.ResponseWriter, pack *language.Pack, e *turnError) {
+	if e.dropped {
+		return
+	}
+
+	var failedResponse any
+	if e.transcript != "" {
+		failedResponse = model.AnswerChatResponse{
+			Language: pack.Code,
+			Prompt: model.Chat{
+				Text: e.transcript,
+			},
+		}
+	}
+
+	switch {
+	case e.budget != nil:
+		sendBudgetError(w, pack, failedResponse, e.budget)
+	case e.session != nil:
+		sendSessionError(w, pack, failedResponse, e.session)
+	default:
+		util.SendResponse(w, failedResponse, e.message, e.status)
+	}
+}
+
+// answerTurn takes the candidate's answer through transcription, the
+// interviewer's reply, scoring and speech, and saves the turn once all of
+// it has been generated.
+func (h *handler) answerTurn(ctx context.Context, user *data.ChatUser, userAnswer *answer, events turnEvents) (*model.AnswerChatResponse, *turnError) {
+	pack := h.packs.Get(user.Language)
+
+	if err := session.Active(user.Status); err != nil {
+		return nil, &turnError{session: err}
+	}
+
+	entries, err := h.db.GetChatsByChatUserID(user.ID)
+	if err != nil {
+		log.Printf("failed to get chat: %v", err)
+		return nil, &turnError{message: "failed to get chat", status: http.StatusInternalServerError}
+	}
+
+	if err := h.budget.CheckTurn(ctx, user, countAnswers(entries), export.Duration(userAnswer.recording, userAnswer.contentType)); err != nil {
+		return nil, &turnError{budget: err}
+	}
+
+	if events.start != nil {
+		if err := events.start(); err != nil {
+			log.Printf("failed to start turn: %v", err)
+			return nil, &turnError{dropped: true}
+		}
+	}
+
+	transcriptText, err := h.transcript(ctx, userAnswer, pack)
+	if err != nil {
+		log.Printf("failed to transcribe speech: %v", err)
+		return nil, &turnError{message: "failed to transcribe speech", status: util.UpstreamStatus(err)}
+	}
+
+	// on failure the transcript is sent back so the answer is not lost
+	fail := func(e *turnError) *turnError {
+		e.transcript = transcriptText
+		return e
+	}
+
+	if events.transcript != nil {
+		if err := events.transcript(transcriptText); err != nil {
+			log.Printf("failed to send transcript: %v", err)
+			return nil, &turnError{dropped: true}
+		}
+	}
+
+	if userAnswer.recording != nil {
+		if err := h.budget.CheckAudio(ctx, user); err != nil {
+			return nil, fail(&turnError{budget: err})
+		}
+	}
+
+	if err := h.budget.CheckSession(ctx, user); err != nil {
+		return nil, fail(&turnError{budget: err})
+	}
+
+	history, err := h.history.Build(ctx, user.ID, entries)
+	if err != nil {
+		log.Printf("failed to build chat history: %v", err)
+		return nil, fail(&turnError{message: "failed to get chat", status: http.StatusInternalServerError})
+	}
+
+	turnProgress := progress(user, countAnswers(entries)+1)
+
+	chatHistory, err := turnMessages(history, pack, turnProgress, transcriptText)
+	if err != nil {
+		log.Printf("failed to render prompt: %v", err)
+		return nil, fail(&turnError{message: "failed to prepare feedback", status: http.StatusInternalServerError})
+	}
+
+	var answerText string
+	if events.token != nil {
+		answerText, err = util.GenerateTextStream(ctx, h.ai, chatHistory, events.token)
+	} else {
+		answerText, err = util.GenerateText(ctx, h.ai, chatHistory)
+	}
+	if err != nil {
+		log.Printf("failed to get chat completion: %v", err)
+		return nil, fail(&turnError{message: "failed to get chat completion", status: util.UpstreamStatus(err)})
+	}
+
+	if events.answer != nil {
+		if err := events.answer(answerText); err != nil {
+			log.Printf("failed to send answer: %v", err)
+			return nil, &turnError{dropped: true}
+		}
+	}
+
+	storedScore, answerScore := h.scoreAnswer(ctx, user, pack, entries, transcriptText)
+
+	if err := h.budget.CheckSession(ctx, user); err != nil {
+		return nil, fail(&turnError{budget: err})
+	}
+
+	// text-only replies have no audio
+	var answerAudio, answerSSML string
+	if !userAnswer.textOnly {
+		speech := events.speech
+		if speech == nil {
+			speech = func(ctx context.Context, user *data.ChatUser, text string) (string, error) {
+				return util.GenerateSpeech(ctx, h.speech, user.Language, text, h.voices(user))
+			}
+		}
+
+		answerAudio, err = speech(ctx, user, answerText)
+		if err != nil {
+			log.Printf("failed to generate speech: %v", err)
+			return nil, fail(&turnError{message: "failed to generate speech", status: util.UpstreamStatus(err)})
+		}
+
+		if answerAudio == "" {
+			answerSSML, err = util.GenerateSSML(ctx, h.ai, answerText)
+			if err != nil {
+				log.Printf("failed to generate ssml: %v", err)
+			}
+		}
+
+		if events.audio != nil {
+			if err := events.audio(answerAudio, answerSSML); err != nil {
+				log.Printf("failed to send audio: %v", err)
+				return nil, &turnError{dropped: true}
+			}
+		}
+	}
+
+	answerAudioKey, answerAudioDuration, err := h.storeAudio(ctx, answerAudio)
+	if err != nil {
+		log.Printf("failed to store audio: %v", err)
+		return nil, fail(&turnError{message: "failed to store audio", status: http.StatusInternalServerError})
+	}
+
+	recordingKey, err := h.storeRecording(ctx, userAnswer)
+	if err != nil {
+		h.discardBlobs(answerAudioKey)
+		log.Printf("failed to store recording: %v", err)
+		return nil, fail(&turnError{message: "failed to store recording", status: http.StatusInternalServerError})
+	}
+
+	// the blobs are only kept once the entries referencing them are stored
+	stored := false
+	defer func() {
+		if !stored {
+			h.discardBlobs(answerAudioKey, recordingKey)
+		}
+	}()
+
+	tx, err := h.db.BeginTx()
+	if err != nil {
+		log.Printf("failed to begin transaction: %v", err)
+		return nil, fail(&turnError{message: "failed to create new chat", status: http.StatusInternalServerError})
+	}
+	defer tx.Rollback()
+
+	// the session can be paused or ended while the turn is generated, once
+	// locked its status holds until the turn is saved
+	status, err := tx.LockChatUser(user.ID)
+	if err != nil {
+		log.Printf("failed to lock session: %v", err)
+		return nil, fail(&turnError{message: "failed to create new chat", status: http.StatusInternalServerError})
+	}
+
+	if err := session.Active(status); err != nil {
+		return nil, fail(&turnError{session: err})
+	}
+
+	// the closing feedback has been given, so the interview is over
+	if turnProgress.Done() {
+		ended, err := tx.UpdateChatUserStatus(user.ID, session.STATUS_ENDED)
+		if err != nil {
+			log.Printf("failed to end session: %v", err)
+			return nil, fail(&turnError{message: "failed to end chat", status: http.StatusInternalServerError})
+		}
+
+		if !ended {
+			return nil, fail(&turnError{session: &session.Error{Code: session.CODE_SESSION_ENDED}})
+		}
+	}
+
+	created, err := tx.CreateChats(user.ID, []data.Entry{
+		{
+			Role:          string(openai.ROLE_USER),
+			Text:          transcriptText,
+			AudioKey:      recordingKey,
+			AudioFormat:   userAnswer.contentType,
+			AudioDuration: userAnswer.duration,
+			Score:         storedScore,
+		},
+		{
+			Role:          string(openai.ROLE_ASSISTANT),
+			Text:          answerText,
+			AudioKey:      answerAudioKey,
+			AudioDuration: answerAudioDuration,
+		},
+	})
+	if err != nil {
+		log.Printf("failed to create chat: %v", err)
+		return nil, fail(&turnError{message: "failed to create chat", status: http.StatusInternalServerError})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("failed to commit transaction: %v", err)
+		return nil, fail(&turnError{message: "failed to create new chat", status: http.StatusInternalServerError})
+	}
+	stored = true
+
+	if turnProgress.Done() {
+		h.endReport(ctx, user)
+	}
+
+	// audio sent along the way is always inline, the response links it when
+	// configured
+	answerAudio, answerAudioURL := h.responseAudio(created[1].ID, answerAudio)
+
+	return &model.AnswerChatResponse{
+		Language: pack.Code,
+		Prompt: model.Chat{
+			Text:         transcriptText,
+			RecordingURL: recordingURL(created[0]),
+			Duration:     userAnswer.duration,
+		},
+		Answer: model.Chat{
+			Text:     answerText,
+			Audio:    answerAudio,
+			AudioURL: answerAudioURL,
+			SSML:     answerSSML,
+		},
+		Score:    answerScore,
+		Progress: progressResponse(turnProgress),
+	}, nil
+}
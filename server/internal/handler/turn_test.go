@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/session"
+)
+
+// racingStore runs race the next time a transaction begins, which for a
+// turn is after its status was checked and before it is saved.
+type racingStore struct {
+	data.Store
+	race func()
+}
+
+func (s *racingStore) BeginTx() (data.Tx, error) {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+
+	return s.Store.BeginTx()
+}
+
+func TestAnswerRacesStatusChange(t *testing.T) {
+	for _, status := range []string{session.STATUS_PAUSED, session.STATUS_ENDED} {
+		t.Run(status, func(t *testing.T) {
+			var store *racingStore
+			server := newTestServerWithStore(t, func(db data.Store) data.Store {
+				store = &racingStore{Store: db}
+				return store
+			})
+
+			s := startSession(t, server, model.StartChatRequest{Role: "Backend Engineer", Skills: []string{"Go"}})
+
+			before, err := store.GetChatsByChatUserID(s.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the race runs on the server's goroutine, where it cannot stop
+			// the test
+			store.race = func() {
+				tx, err := store.Store.BeginTx()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer tx.Rollback()
+
+				if updated, err := tx.UpdateChatUserStatus(s.id, status); err != nil || !updated {
+					t.Errorf("failed to move the session to %s: %v", status, err)
+					return
+				}
+
+				if err := tx.Commit(); err != nil {
+					t.Error(err)
+				}
+			}
+
+			s.answerText("I built a payment service in Go.", http.StatusConflict)
+
+			after, err := store.GetChatsByChatUserID(s.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(after) != len(before) {
+				t.Errorf("got %d entries, want the %d from before the answer", len(after), len(before))
+			}
+
+			if current := s.session().Status; current != status {
+				t.Errorf("got status %q, want %q", current, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/model"
+	"github.com/madeindra/mock-interview/server/internal/usage"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+const (
+	defaultUsagePeriod = 30 * 24 * time.Hour
+	adminUsageSessions = 50
+)
+
+// saveUsage stores what the AI calls made with ctx consumed. It runs after
+// failed requests too, since the calls that did succeed are still billed.
+func (h *handler) saveUsage(ctx context.Context, chatUserID, client string) {
+	recorder := usage.FromContext(ctx)
+	if recorder == nil {
+		return
+	}
+
+	records := recorder.Drain()
+	if len(records) == 0 {
+		return
+	}
+
+	usages := make([]data.Usage, len(records))
+	for i, record := range records {
+		usages[i] = data.Usage{
+			ChatUserID:   chatUserID,
+			Kind:         string(record.Kind),
+			Provider:     record.Provider,
+			Model:        record.Model,
+			InputTokens:  record.InputTokens,
+			OutputTokens: record.OutputTokens,
+			AudioSeconds: record.AudioSeconds,
+			Characters:   record.Characters,
+			Cost:         h.prices.Cost(record),
+			Client:       client,
+		}
+	}
+
+	if err := h.db.CreateUsages(usages); err != nil {
+		log.Printf("failed to save usage: %v", err)
+	}
+}
+
+func (h *handler) GetUsage(w http.ResponseWriter, req *http.Request) {
+	user, ok := h.getChatUser(w, req)
+	if !ok {
+		return
+	}
+
+	breakdown, err := h.db.GetUsageTotalsByChatUserID(user.ID)
+	if err != nil {
+		log.Printf("failed to get usage: %v", err)
+		util.SendResponse(w, nil, "failed to get usage", http.StatusInternalServerError)
+
+		return
+	}
+
+	response := model.UsageResponse{
+		Total:     sumUsage(breakdown),
+		Breakdown: breakdown,
+	}
+
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
+
+// GetAdminUsage reports usage across all sessions between the optional
+// `from` and `to` query parameters, dates or RFC 3339 timestamps, which
+// default to the last 30 days.
+func (h *handler) GetAdminUsage(w http.ResponseWriter, req *http.Request) {
+	to, err := parseTime(req.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		util.SendResponse(w, nil, "invalid to parameter", http.StatusBadRequest)
+
+		return
+	}
+
+	from, err := parseTime(req.URL.Query().Get("from"), to.Add(-defaultUsagePeriod))
+	if err != nil {
+		util.SendResponse(w, nil, "invalid from parameter", http.StatusBadRequest)
+
+		return
+	}
+
+	breakdown, err := h.db.GetUsageTotals(from, to)
+	if err != nil {
+		log.Printf("failed to get usage: %v", err)
+		util.SendResponse(w, nil, "failed to get usage", http.StatusInternalServerError)
+
+		return
+	}
+
+	sessions, err := h.db.GetUsageTotalsByChatUser(from, to, adminUsageSessions)
+	if err != nil {
+		log.Printf("failed to get usage per session: %v", err)
+		util.SendResponse(w, nil, "failed to get usage", http.StatusInternalServerError)
+
+		return
+	}
+
+	response := model.AdminUsageResponse{
+		From:      from,
+		To:        to,
+		Total:     sumUsage(breakdown),
+		Breakdown: breakdown,
+		Sessions:  sessions,
+	}
+
+	util.SendResponse(w, response, "success", http.StatusOK)
+}
+
+func sumUsage(totals []data.UsageTotal) data.UsageTotal {
+	var sum data.UsageTotal
+	for _, total := range totals {
+		sum.Calls += total.Calls
+		sum.InputTokens += total.InputTokens
+		sum.OutputTokens += total.OutputTokens
+		sum.AudioSeconds += total.AudioSeconds
+		sum.Characters += total.Characters
+		sum.Cost += total.Cost
+	}
+
+	return sum
+}
+
+func parseTime(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,127 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/util"
+)
+
+const summaryPrefix = "Summary of the earlier part of this interview:\n"
+
+// Manager keeps the prompt history within a token budget. System prompts
+// and the most recent messages are always sent verbatim, older turns are
+// folded into a rolling summary that is stored once and reused until the
+// history outgrows the budget again.
+type Manager struct {
+	ai openai.Client
+	db data.Store
+
+	maxTokens      int
+	recentMessages int
+}
+
+func NewManager(ai openai.Client, db data.Store, maxTokens, recentMessages int) *Manager {
+	return &Manager{
+		ai:             ai,
+		db:             db,
+		maxTokens:      maxTokens,
+		recentMessages: recentMessages,
+	}
+}
+
+// Build returns the messages to send for the given stored entries. A
+// failure to summarize is not fatal, the unsummarized history is used.
+func (m *Manager) Build(ctx context.Context, chatUserID string, entries []data.Entry) ([]openai.ChatMessage, error) {
+	system, conversation := split(util.ConvertToChatMessage(entries))
+
+	summary, err := m.db.GetSummary(chatUserID)
+	if err != nil && !errors.Is(err, data.ErrNotFound) {
+		return nil, err
+	}
+
+	var summaryText string
+	var covered int
+	if summary != nil && summary.EntryCount <= len(conversation) {
+		summaryText = summary.Text
+		covered = summary.EntryCount
+	}
+
+	messages := assemble(system, summaryText, conversation[covered:])
+	if m.maxTokens <= 0 || openai.CountAllTokens(messages) <= m.maxTokens {
+		return messages, nil
+	}
+
+	keepFrom := max(len(conversation)-m.recentMessages, 0)
+	if keepFrom <= covered {
+		return messages, nil
+	}
+
+	newSummary, err := m.summarize(ctx, summaryText, conversation[covered:keepFrom])
+	if err != nil {
+		log.Printf("failed to summarize history: %v", err)
+		return messages, nil
+	}
+
+	if _, err := m.db.SaveSummary(chatUserID, newSummary, keepFrom); err != nil {
+		log.Printf("failed to save history summary: %v", err)
+	}
+
+	return assemble(system, newSummary, conversation[keepFrom:]), nil
+}
+
+func (m *Manager) summarize(ctx context.Context, previous string, messages []openai.ChatMessage) (string, error) {
+	var transcript strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&transcript, "Summary so far:\n%s\n\nContinuation:\n", previous)
+	}
+
+	for _, message := range messages {
+		speaker := "Candidate"
+		if message.Role == openai.ROLE_ASSISTANT {
+			speaker = "Interviewer"
+		}
+
+		fmt.Fprintf(&transcript, "%s: %s\n", speaker, message.Content)
+	}
+
+	return util.GenerateText(ctx, m.ai, []openai.ChatMessage{
+		{
+			Role:    openai.ROLE_SYSTEM,
+			Content: openai.GetSummaryPrompt(),
+		},
+		{
+			Role:    openai.ROLE_USER,
+			Content: transcript.String(),
+		},
+	})
+}
+
+// split separates the leading system prompts from the conversation.
+func split(messages []openai.ChatMessage) ([]openai.ChatMessage, []openai.ChatMessage) {
+	var i int
+	for i < len(messages) && messages[i].Role == openai.ROLE_SYSTEM {
+		i++
+	}
+
+	return messages[:i], messages[i:]
+}
+
+func assemble(system []openai.ChatMessage, summary string, recent []openai.ChatMessage) []openai.ChatMessage {
+	messages := make([]openai.ChatMessage, 0, len(system)+len(recent)+1)
+	messages = append(messages, system...)
+
+	if summary != "" {
+		messages = append(messages, openai.ChatMessage{
+			Role:    openai.ROLE_SYSTEM,
+			Content: summaryPrefix + summary,
+		})
+	}
+
+	return append(messages, recent...)
+}
@@ -0,0 +1,336 @@
+package language
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/tts"
+)
+
+const DEFAULT_LANGUAGE = "en"
+
+// Sections of the final feedback that can be asked for.
+const (
+	SECTION_STRENGTHS    = "strengths"
+	SECTION_IMPROVEMENTS = "improvements"
+	SECTION_FIT          = "fit"
+)
+
+var Sections = []string{SECTION_STRENGTHS, SECTION_IMPROVEMENTS, SECTION_FIT}
+
+const (
+	packFile     = "pack.json"
+	systemFile   = "system.txt"
+	greetingFile = "greeting.txt"
+	endFile      = "end.txt"
+	progressFile = "progress.txt"
+)
+
+//go:embed packs
+var defaultPacks embed.FS
+
+// Pack is everything needed to hold an interview in one language. Its
+// Language is the name of the pack's directory and is what sessions store.
+type Pack struct {
+	Language string `json:"-"`
+	// Code is the BCP-47 tag clients select the language with
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// Transcription is the ISO-639-1 language passed to speech recognition
+	Transcription string            `json:"transcription"`
+	TTS           []tts.Voice       `json:"tts"`
+	Messages      map[string]string `json:"messages"`
+
+	SystemPrompt string `json:"-"`
+	Greeting     string `json:"-"`
+	EndPrompt    string `json:"-"`
+	// ProgressPrompt falls back to the default pack's when a pack has none
+	ProgressPrompt string `json:"-"`
+}
+
+// RenderSystemPrompt fills in the system prompt template.
+func (p *Pack) RenderSystemPrompt(role string, skills []string, name, style string) (string, error) {
+	return render(p.SystemPrompt, struct {
+		Role   string
+		Skills string
+		Name   string
+		Style  string
+	}{
+		Role:   role,
+		Skills: strings.Join(skills, ";"),
+		Name:   name,
+		Style:  style,
+	})
+}
+
+// RenderGreeting fills in the greeting, a non-empty greeting template
+// replaces the pack's own.
+func (p *Pack) RenderGreeting(role, name, greeting string) (string, error) {
+	if greeting == "" {
+		greeting = p.Greeting
+	}
+
+	return render(greeting, struct {
+		Role string
+		Name string
+	}{
+		Role: role,
+		Name: name,
+	})
+}
+
+// RenderEndPrompt fills in the closing instruction asking for the given
+// feedback sections, in the pack's language.
+func (p *Pack) RenderEndPrompt(sections []string) (string, error) {
+	data := struct {
+		Language     string
+		Strengths    bool
+		Improvements bool
+		Fit          bool
+	}{
+		Language: p.Name,
+	}
+
+	for _, section := range sections {
+		switch section {
+		case SECTION_STRENGTHS:
+			data.Strengths = true
+		case SECTION_IMPROVEMENTS:
+			data.Improvements = true
+		case SECTION_FIT:
+			data.Fit = true
+		default:
+			return "", fmt.Errorf("unknown feedback section %q", section)
+		}
+	}
+
+	return render(p.EndPrompt, data)
+}
+
+// RenderProgressPrompt fills in the note telling the interviewer how much of
+// the interview is left, remaining and remainingQuestions are nil for limits
+// that are not set. It is empty when neither is.
+func (p *Pack) RenderProgressPrompt(elapsed time.Duration, remaining *time.Duration, answered int, remainingQuestions *int) (string, error) {
+	if remaining == nil && remainingQuestions == nil {
+		return "", nil
+	}
+
+	data := struct {
+		TimeLimit        bool
+		ElapsedMinutes   int
+		RemainingMinutes int
+
+		QuestionLimit      bool
+		Answered           int
+		RemainingQuestions int
+	}{
+		ElapsedMinutes: int(elapsed.Minutes()),
+		Answered:       answered,
+	}
+
+	if remaining != nil {
+		data.TimeLimit = true
+		data.RemainingMinutes = int(remaining.Round(time.Minute).Minutes())
+	}
+
+	if remainingQuestions != nil {
+		data.QuestionLimit = true
+		data.RemainingQuestions = *remainingQuestions
+	}
+
+	return render(p.ProgressPrompt, data)
+}
+
+// Message returns the pack's translation of an API message, or the given
+// English one.
+func (p *Pack) Message(key, message string) string {
+	if translated, ok := p.Messages[key]; ok {
+		return translated
+	}
+
+	return message
+}
+
+func render(text string, data any) (string, error) {
+	t, err := template.New("pack").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Registry holds the available packs, sorted by language.
+type Registry struct {
+	packs  []*Pack
+	byLang map[string]*Pack
+}
+
+// Load reads the embedded packs, then the packs found in dir, if any. A pack
+// in dir replaces the embedded pack of the same language.
+func Load(dir string) (*Registry, error) {
+	r := &Registry{byLang: make(map[string]*Pack)}
+
+	packs, err := fs.Sub(defaultPacks, "packs")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadDir(packs); err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		if err := r.loadDir(os.DirFS(dir)); err != nil {
+			return nil, err
+		}
+	}
+
+	defaultPack, ok := r.byLang[DEFAULT_LANGUAGE]
+	if !ok {
+		return nil, fmt.Errorf("default language pack %q is missing", DEFAULT_LANGUAGE)
+	}
+
+	if defaultPack.ProgressPrompt == "" {
+		return nil, fmt.Errorf("default language pack %q has no %s", DEFAULT_LANGUAGE, progressFile)
+	}
+
+	for _, pack := range r.byLang {
+		if pack.ProgressPrompt == "" {
+			pack.ProgressPrompt = defaultPack.ProgressPrompt
+		}
+
+		r.packs = append(r.packs, pack)
+	}
+	sort.Slice(r.packs, func(i, j int) bool {
+		return r.packs[i].Language < r.packs[j].Language
+	})
+
+	return r, nil
+}
+
+func (r *Registry) loadDir(dir fs.FS) error {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		pack, err := loadPack(dir, entry.Name())
+		if err != nil {
+			return fmt.Errorf("language pack %s: %w", entry.Name(), err)
+		}
+
+		r.byLang[pack.Language] = pack
+	}
+
+	return nil
+}
+
+func loadPack(dir fs.FS, language string) (*Pack, error) {
+	file, err := fs.ReadFile(dir, language+"/"+packFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pack := &Pack{Language: language}
+	if err := json.Unmarshal(file, pack); err != nil {
+		return nil, err
+	}
+
+	if pack.Code == "" {
+		return nil, fmt.Errorf("code is missing")
+	}
+	if pack.Transcription == "" {
+		pack.Transcription = language
+	}
+	if err := (tts.Routes{language: pack.TTS}).Validate(); err != nil {
+		return nil, err
+	}
+
+	templates := []struct {
+		name     string
+		text     *string
+		optional bool
+	}{
+		{systemFile, &pack.SystemPrompt, false},
+		{greetingFile, &pack.Greeting, false},
+		{endFile, &pack.EndPrompt, false},
+		// packs written before progress prompts existed have none
+		{progressFile, &pack.ProgressPrompt, true},
+	}
+
+	for _, t := range templates {
+		text, err := fs.ReadFile(dir, language+"/"+t.name)
+		if t.optional && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := template.New(t.name).Parse(string(text)); err != nil {
+			return nil, err
+		}
+
+		*t.text = string(text)
+	}
+
+	return pack, nil
+}
+
+func (r *Registry) List() []*Pack {
+	return r.packs
+}
+
+// Find returns the pack of a BCP-47 code, or of a bare language.
+func (r *Registry) Find(code string) (*Pack, bool) {
+	for _, pack := range r.packs {
+		if strings.EqualFold(pack.Code, code) {
+			return pack, true
+		}
+	}
+
+	pack, ok := r.byLang[strings.ToLower(code)]
+	return pack, ok
+}
+
+// Get returns the pack of a stored session, sessions whose pack was since
+// removed get the default language.
+func (r *Registry) Get(language string) *Pack {
+	if pack, ok := r.byLang[language]; ok {
+		return pack
+	}
+
+	return r.byLang[DEFAULT_LANGUAGE]
+}
+
+// Routes are the speech routes of the packs that define one.
+func (r *Registry) Routes() tts.Routes {
+	routes := make(tts.Routes)
+	for _, pack := range r.packs {
+		if len(pack.TTS) > 0 {
+			routes[pack.Language] = pack.TTS
+		}
+	}
+
+	return routes
+}
@@ -14,16 +14,35 @@ const (
 	ContextKeyUserSecret contextKey = "user-secret"
 )
 
+const (
+	// WebSocketProtocol is the subprotocol a WebSocket client must offer
+	// next to its credentials, the server selects it on upgrade
+	WebSocketProtocol = "mock-interview"
+
+	// webSocketAuthPrefix marks the subprotocol carrying the unpadded
+	// base64url encoded id:secret
+	webSocketAuthPrefix = "basic."
+)
+
 func BasicAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		accessKey := r.Header.Get("Authorization")
 
+		// browsers cannot set headers on a WebSocket handshake, so the
+		// encoded credentials may be offered as a subprotocol instead, which
+		// unlike a query parameter does not end up in access logs
+		if accessKey == "" && isWebSocketUpgrade(r) {
+			if token, ok := webSocketToken(r); ok {
+				accessKey = "Basic " + token
+			}
+		}
+
 		if accessKey == "" {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		if accessKey[:6] != "Basic " {
+		if !strings.HasPrefix(accessKey, "Basic ") {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -51,3 +70,29 @@ func BasicAuth(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// webSocketToken returns the credentials offered in Sec-WebSocket-Protocol
+// re-encoded for the Authorization header.
+func webSocketToken(r *http.Request) (string, bool) {
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			token, ok := strings.CutPrefix(strings.TrimSpace(protocol), webSocketAuthPrefix)
+			if !ok {
+				continue
+			}
+
+			decoded, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				return "", false
+			}
+
+			return base64.StdEncoding.EncodeToString(decoded), true
+		}
+	}
+
+	return "", false
+}
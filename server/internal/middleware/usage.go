@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/madeindra/mock-interview/server/internal/usage"
+)
+
+// TrackUsage gives every request a recorder collecting the usage of the
+// AI calls made while handling it.
+func TrackUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(usage.NewContext(r.Context(), &usage.Recorder{}))
+
+		next.ServeHTTP(w, r)
+	})
+}
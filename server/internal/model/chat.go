@@ -2,6 +2,12 @@ package model
 
 type Chat struct {
 	Audio string `json:"audio,omitempty"`
-	SSML  string `json:"ssml,omitempty"`
-	Text  string `json:"text,omitempty"`
+	// AudioURL replaces Audio when audio is served from GET /chat/audio
+	AudioURL string `json:"audioUrl,omitempty"`
+	SSML     string `json:"ssml,omitempty"`
+	Text     string `json:"text,omitempty"`
+	// RecordingURL downloads the candidate's recording of an answer, Duration
+	// is how many seconds the recording or the audio lasts
+	RecordingURL string  `json:"recordingUrl,omitempty"`
+	Duration     float64 `json:"duration,omitempty"`
 }
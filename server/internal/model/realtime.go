@@ -0,0 +1,23 @@
+package model
+
+import "github.com/madeindra/mock-interview/server/internal/rubric"
+
+// RealtimeMessage is the JSON text frame exchanged over the realtime
+// WebSocket, audio itself is sent as binary frames.
+type RealtimeMessage struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	SSML       string `json:"ssml,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Language   string `json:"language,omitempty"`
+	SampleRate int    `json:"sampleRate,omitempty"`
+
+	Score    *rubric.Score `json:"score,omitempty"`
+	Progress *Progress     `json:"progress,omitempty"`
+}
+
+const (
+	MESSAGE_CONFIG = "config"
+	MESSAGE_COMMIT = "commit"
+)
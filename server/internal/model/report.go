@@ -0,0 +1,12 @@
+package model
+
+import (
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/report"
+)
+
+type ReportResponse struct {
+	report.Report
+	CreatedAt time.Time `json:"createdAt"`
+}
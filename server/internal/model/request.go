@@ -4,4 +4,26 @@ type StartChatRequest struct {
 	Role     string   `json:"role"`
 	Skills   []string `json:"skills"`
 	Language string   `json:"language"`
+	// Persona is the interviewer's ID from the catalog, default if empty
+	Persona string `json:"persona"`
+	// Scoring scores every answer against the rubric, practice mode returns
+	// the score with each answer while realistic mode keeps it for the end
+	Scoring bool   `json:"scoring"`
+	Mode    string `json:"mode"`
+	// DurationMinutes and MaxQuestions end the interview with the closing
+	// feedback once reached, zero is unlimited
+	DurationMinutes int `json:"durationMinutes"`
+	MaxQuestions    int `json:"maxQuestions"`
 }
+
+// AnswerChatRequest is a typed answer, TextOnly skips speech synthesis of
+// the reply.
+type AnswerChatRequest struct {
+	Text     string `json:"text"`
+	TextOnly bool   `json:"textOnly"`
+}
+
+const (
+	MODE_REALISTIC = "realistic"
+	MODE_PRACTICE  = "practice"
+)
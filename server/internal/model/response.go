@@ -1,14 +1,26 @@
 package model
 
+import (
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/rubric"
+)
+
 type Response struct {
 	Message string `json:"message,omitempty"`
-	Data    any    `json:"data,omitempty"`
+	// Code identifies the error for clients, set for errors they can act on
+	Code string `json:"code,omitempty"`
+	Data any    `json:"data,omitempty"`
 }
 
 type StartChatResponse struct {
 	ID       string `json:"id"`
 	Secret   string `json:"secret"`
 	Language string `json:"language"`
+	Persona  string `json:"persona"`
+	Mode     string `json:"mode"`
+	// Progress is only sent for interviews with a duration or question limit
+	Progress *Progress `json:"progress,omitempty"`
 
 	Chat
 }
@@ -17,11 +29,89 @@ type AnswerChatResponse struct {
 	Language string `json:"language"`
 	Prompt   Chat   `json:"prompt,omitempty"`
 	Answer   Chat   `json:"answer,omitempty"`
+	// Score is the rubric score of the prompt, only sent in practice mode
+	Score *rubric.Score `json:"score,omitempty"`
+	// Scores lists the score of every answer once the interview has ended
+	Scores   []AnswerScore `json:"scores,omitempty"`
+	Progress *Progress     `json:"progress,omitempty"`
+}
+
+// Progress counts down the limits of an interview, Ended is set when the
+// answer hit one and the interviewer replied with the closing feedback.
+type Progress struct {
+	ElapsedSeconds     int  `json:"elapsedSeconds"`
+	RemainingSeconds   *int `json:"remainingSeconds,omitempty"`
+	RemainingQuestions *int `json:"remainingQuestions,omitempty"`
+	Ended              bool `json:"ended"`
+	// OverTime is set once the time limit has run out, the next answer or
+	// end closes the interview
+	OverTime bool `json:"overTime"`
+}
+
+type AnswerScore struct {
+	EntryID string `json:"entryId"`
+	Text    string `json:"text"`
+
+	rubric.Score
+}
+
+// HistoryResponse is a page of the interview's turns, Total counts them all.
+type HistoryResponse struct {
+	Language string `json:"language"`
+	Status   string `json:"status"`
+	Turns    []Turn `json:"turns"`
+	Total    int    `json:"total"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+}
+
+type Turn struct {
+	EntryID   string     `json:"entryId"`
+	Seq       int        `json:"seq"`
+	Role      string     `json:"role"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	// Score is only sent in practice mode or once the interview is over
+	Score *rubric.Score `json:"score,omitempty"`
+
+	Chat
+}
+
+type SessionResponse struct {
+	Status    string     `json:"status"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Progress  *Progress  `json:"progress,omitempty"`
+}
+
+type PersonaResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Gender      string `json:"gender"`
+	Description string `json:"description"`
+}
+
+type LanguageResponse struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
 }
 
 type StatusResponse struct {
-	Server    bool    `json:"backend"`
-	API       *bool   `json:"api"`
-	ApiStatus *string `json:"apiStatus"`
-	Key       bool    `json:"key"`
+	Server    bool              `json:"backend"`
+	API       *bool             `json:"api"`
+	ApiStatus *string           `json:"apiStatus"`
+	Key       bool              `json:"key"`
+	Circuits  map[string]string `json:"circuits"`
 }
+
+const (
+	EVENT_TRANSCRIPT = "transcript"
+	EVENT_TOKEN      = "token"
+	EVENT_ANSWER     = "answer"
+	EVENT_AUDIO      = "audio"
+	EVENT_DONE       = "done"
+	EVENT_ERROR      = "error"
+
+	EVENT_READY          = "ready"
+	EVENT_SPEECH_STARTED = "speech_started"
+	EVENT_AUDIO_END      = "audio_end"
+)
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+)
+
+type UsageResponse struct {
+	Total     data.UsageTotal   `json:"total"`
+	Breakdown []data.UsageTotal `json:"breakdown"`
+}
+
+type AdminUsageResponse struct {
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Total     data.UsageTotal   `json:"total"`
+	Breakdown []data.UsageTotal `json:"breakdown"`
+	Sessions  []data.UsageTotal `json:"sessions"`
+}
@@ -1,86 +1,150 @@
 package openai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
-	_ "embed"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+	"github.com/madeindra/mock-interview/server/internal/usage"
 )
 
 type Client interface {
-	IsKeyValid() (bool, error)
-	Status() (Status, error)
-	Chat([]ChatMessage) (string, error)
-	TextToSpeech(string) (io.ReadCloser, error)
-	Transcribe(io.ReadCloser, string, string) (TranscriptResponse, error)
-
-	SSML(string) (string, error)
-
-	GetDefaultTranscriptLanguage() string
-	IsSpeechAvailable(string) bool
+	IsKeyValid(context.Context) (bool, error)
+	Status(context.Context) (Status, error)
+	Chat(context.Context, []ChatMessage) (string, error)
+	ChatStream(context.Context, []ChatMessage, func(string) error) (string, error)
+	ChatJSON(context.Context, []ChatMessage, JSONSchema) (string, error)
+	TextToSpeech(context.Context, string, SpeechOptions) (io.ReadCloser, error)
+	Transcribe(context.Context, io.ReadCloser, string, string) (TranscriptResponse, error)
+
+	SSML(context.Context, string) (string, error)
 }
 
 type OpenAI struct {
+	name               string
 	apiKey             string
 	baseURL            string
+	statusURL          string
 	chatModel          string
 	transcriptModel    string
 	transcriptLanguage string
 	ttsModel           string
 	ttsVoice           string
+
+	httpClient *http.Client
+	timeout    time.Duration
 }
 
-const (
-	baseURL            = "https://api.openai.com/v1"
-	statusURL          = "https://status.openai.com/api/v2"
-	chatModel          = "gpt-4o-mini-2024-07-18"
-	transcriptModel    = "whisper-1"
-	transcriptLanguage = "en"
-	ttsModel           = "tts-1"
-	ttsVoice           = "nova"
-)
+type Option func(*OpenAI)
 
-var supportedTranscriptLanguages = map[string]struct{}{
-	"en": {},
+// WithHTTPClient replaces http.DefaultClient for every request.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *OpenAI) {
+		c.httpClient = httpClient
+	}
 }
 
-//go:embed templates/ssml.prompt.txt
-var ssmlPrompt string
+// WithTimeout bounds every call, including reading a streamed response.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *OpenAI) {
+		c.timeout = timeout
+	}
+}
 
-func NewOpenAI(apiKey string) *OpenAI {
-	return &OpenAI{
+// WithSpeechModels replaces the transcription and speech models, empty ones
+// keep the defaults.
+func WithSpeechModels(transcriptModel, ttsModel string) Option {
+	return func(c *OpenAI) {
+		if transcriptModel != "" {
+			c.transcriptModel = transcriptModel
+		}
+
+		if ttsModel != "" {
+			c.ttsModel = ttsModel
+		}
+	}
+}
+
+const (
+	providerName           = "openai"
+	compatibleProviderName = "openai-compatible"
+	baseURL                = "https://api.openai.com/v1"
+	statusURL              = "https://status.openai.com/api/v2"
+	chatModel              = "gpt-4o-mini-2024-07-18"
+	transcriptModel        = "whisper-1"
+	transcriptLanguage     = "en"
+	ttsModel               = "tts-1"
+	ttsVoice               = "nova"
+)
+
+func NewOpenAI(apiKey string, opts ...Option) *OpenAI {
+	client := &OpenAI{
+		name:               providerName,
 		apiKey:             apiKey,
 		baseURL:            baseURL,
+		statusURL:          statusURL,
 		chatModel:          chatModel,
 		transcriptModel:    transcriptModel,
 		ttsModel:           ttsModel,
 		ttsVoice:           ttsVoice,
 		transcriptLanguage: transcriptLanguage,
+		httpClient:         http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
+
+// NewOpenAICompatible creates a client for a server exposing the OpenAI API
+// under a different base URL, e.g. a self-hosted vLLM or Ollama instance.
+// The API key may be empty for servers that do not require authentication.
+func NewOpenAICompatible(apiKey, baseURL, chatModel string, opts ...Option) *OpenAI {
+	client := NewOpenAI(apiKey, opts...)
+	client.name = compatibleProviderName
+	client.baseURL = baseURL
+	client.statusURL = ""
+
+	if chatModel != "" {
+		client.chatModel = chatModel
 	}
+
+	return client
 }
 
-func (c *OpenAI) IsKeyValid() (bool, error) {
+func (c *OpenAI) IsKeyValid(ctx context.Context) (bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	url, err := url.JoinPath(c.baseURL, "/models")
 	if err != nil {
 		return false, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return false, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	c.setAuthorization(req)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return false, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return false, nil
@@ -89,18 +153,25 @@ func (c *OpenAI) IsKeyValid() (bool, error) {
 	return true, nil
 }
 
-func (c *OpenAI) Status() (Status, error) {
-	url, err := url.JoinPath(statusURL, "/components.json")
+func (c *OpenAI) Status(ctx context.Context) (Status, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if c.statusURL == "" {
+		return STATUS_UNKNOWN, nil
+	}
+
+	url, err := url.JoinPath(c.statusURL, "/components.json")
 	if err != nil {
 		return STATUS_UNKNOWN, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return STATUS_UNKNOWN, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return STATUS_UNKNOWN, err
 	}
@@ -133,31 +204,48 @@ func (c *OpenAI) Status() (Status, error) {
 	return STATUS_UNKNOWN, nil
 }
 
-func (c *OpenAI) Chat(messages []ChatMessage) (string, error) {
+func (c *OpenAI) Chat(ctx context.Context, messages []ChatMessage) (string, error) {
+	return c.complete(ctx, ChatRequest{
+		Messages: messages,
+	})
+}
+
+// ChatJSON returns a completion that is JSON matching the schema.
+func (c *OpenAI) ChatJSON(ctx context.Context, messages []ChatMessage, schema JSONSchema) (string, error) {
+	return c.complete(ctx, ChatRequest{
+		Messages: messages,
+		ResponseFormat: &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &schema,
+		},
+	})
+}
+
+func (c *OpenAI) complete(ctx context.Context, chatReq ChatRequest) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	url, err := url.JoinPath(c.baseURL, "/chat/completions")
 	if err != nil {
 		return "", err
 	}
 
-	chatReq := ChatRequest{
-		Model:    c.chatModel,
-		Messages: messages,
-	}
+	chatReq.Model = c.chatModel
 
 	body, err := json.Marshal(chatReq)
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	c.setAuthorization(req)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -168,6 +256,8 @@ func (c *OpenAI) Chat(messages []ChatMessage) (string, error) {
 		return "", err
 	}
 
+	c.recordChat(ctx, chatResp.Usage)
+
 	if len(chatResp.Choices) == 0 {
 		return "", fmt.Errorf("no valid response returned")
 	}
@@ -175,16 +265,120 @@ func (c *OpenAI) Chat(messages []ChatMessage) (string, error) {
 	return chatResp.Choices[0].Message.Content, nil
 }
 
-func (c *OpenAI) TextToSpeech(input string) (io.ReadCloser, error) {
+// ChatStream requests a streamed completion, calling onDelta with every
+// chunk of text as it arrives, and returns the complete text at the end.
+func (c *OpenAI) ChatStream(ctx context.Context, messages []ChatMessage, onDelta func(string) error) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	url, err := url.JoinPath(c.baseURL, "/chat/completions")
+	if err != nil {
+		return "", err
+	}
+
+	chatReq := ChatRequest{
+		Model:         c.chatModel,
+		Messages:      messages,
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+
+	c.setAuthorization(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	respBody, err := getResponseBody(resp)
+	if err != nil {
+		return "", err
+	}
+	defer respBody.Close()
+
+	var text strings.Builder
+
+	scanner := bufio.NewScanner(respBody)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ChatStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", err
+		}
+
+		// the usage comes alone in the last chunk
+		if chunk.Usage != nil {
+			c.recordChat(ctx, *chunk.Usage)
+		}
+
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		text.WriteString(delta)
+
+		if err := onDelta(delta); err != nil {
+			return "", err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no valid response returned")
+	}
+
+	return text.String(), nil
+}
+
+// SpeechOptions override the client's voice, model and audio format for a
+// single call, empty fields keep the defaults.
+type SpeechOptions struct {
+	Voice  string
+	Model  string
+	Format string
+}
+
+func (c *OpenAI) TextToSpeech(ctx context.Context, input string, opts SpeechOptions) (io.ReadCloser, error) {
 	url, err := url.JoinPath(c.baseURL, "/audio/speech")
 	if err != nil {
 		return nil, err
 	}
 
 	ttsReq := TTSRequest{
-		Model: c.ttsModel,
-		Voice: c.ttsVoice,
-		Input: input,
+		Model:          c.ttsModel,
+		Voice:          c.ttsVoice,
+		Input:          input,
+		ResponseFormat: opts.Format,
+	}
+	if opts.Model != "" {
+		ttsReq.Model = opts.Model
+	}
+	if opts.Voice != "" {
+		ttsReq.Voice = opts.Voice
 	}
 
 	body, err := json.Marshal(ttsReq)
@@ -192,32 +386,48 @@ func (c *OpenAI) TextToSpeech(input string) (io.ReadCloser, error) {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewBuffer(body))
+	ctx, cancel := c.withTimeout(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	c.setAuthorization(req)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	respBody, err := getResponseBody(resp)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return respBody, nil
+	usage.Add(ctx, usage.Record{
+		Kind:       usage.KIND_SPEECH,
+		Provider:   c.name,
+		Model:      ttsReq.Model,
+		Characters: utf8.RuneCountInString(input),
+	})
+
+	// the deadline also covers reading the audio, so it ends on Close
+	return &cancelOnClose{ReadCloser: respBody, cancel: cancel}, nil
 }
 
-func (c *OpenAI) Transcribe(file io.ReadCloser, filename, language string) (TranscriptResponse, error) {
+func (c *OpenAI) Transcribe(ctx context.Context, file io.ReadCloser, filename, language string) (TranscriptResponse, error) {
 	if file == nil {
 		return TranscriptResponse{}, fmt.Errorf("audio is nil")
 	}
 	defer file.Close()
 
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	url, err := url.JoinPath(c.baseURL, "/audio/transcriptions")
 	if err != nil {
 		return TranscriptResponse{}, err
@@ -251,20 +461,26 @@ func (c *OpenAI) Transcribe(file io.ReadCloser, filename, language string) (Tran
 		return TranscriptResponse{}, err
 	}
 
+	// the verbose format includes the audio duration, which is billed
+	err = writer.WriteField("response_format", "verbose_json")
+	if err != nil {
+		return TranscriptResponse{}, err
+	}
+
 	err = writer.Close()
 	if err != nil {
 		return TranscriptResponse{}, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
 		return TranscriptResponse{}, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	c.setAuthorization(req)
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return TranscriptResponse{}, err
 	}
@@ -279,10 +495,20 @@ func (c *OpenAI) Transcribe(file io.ReadCloser, filename, language string) (Tran
 		return TranscriptResponse{}, err
 	}
 
+	usage.Add(ctx, usage.Record{
+		Kind:         usage.KIND_TRANSCRIPTION,
+		Provider:     c.name,
+		Model:        c.transcriptModel,
+		AudioSeconds: transcriptResp.Duration,
+	})
+
 	return transcriptResp, nil
 }
 
-func (c *OpenAI) SSML(text string) (string, error) {
+func (c *OpenAI) SSML(ctx context.Context, text string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	url, err := url.JoinPath(c.baseURL, "/chat/completions")
 	if err != nil {
 		return "", err
@@ -293,7 +519,7 @@ func (c *OpenAI) SSML(text string) (string, error) {
 		Messages: []ChatMessage{
 			{
 				Role:    ROLE_SYSTEM,
-				Content: ssmlPrompt,
+				Content: GetSSMLPrompt(),
 			},
 			{
 				Role:    ROLE_USER,
@@ -307,15 +533,15 @@ func (c *OpenAI) SSML(text string) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	c.setAuthorization(req)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -326,6 +552,8 @@ func (c *OpenAI) SSML(text string) (string, error) {
 		return "", err
 	}
 
+	c.recordChat(ctx, chatResp.Usage)
+
 	if len(chatResp.Choices) == 0 {
 		return "", fmt.Errorf("no valid response returned")
 	}
@@ -333,13 +561,40 @@ func (c *OpenAI) SSML(text string) (string, error) {
 	return chatResp.Choices[0].Message.Content, nil
 }
 
-func (c *OpenAI) GetDefaultTranscriptLanguage() string {
-	return string(c.transcriptLanguage)
+func (c *OpenAI) recordChat(ctx context.Context, u Usage) {
+	usage.Add(ctx, usage.Record{
+		Kind:         usage.KIND_CHAT,
+		Provider:     c.name,
+		Model:        c.chatModel,
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+	})
 }
 
-func (c *OpenAI) IsSpeechAvailable(lang string) bool {
-	_, ok := supportedTranscriptLanguages[lang]
-	return ok
+func (c *OpenAI) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *OpenAI) setAuthorization(req *http.Request) {
+	if c.apiKey == "" {
+		return
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
 }
 
 func getResponseBody(resp *http.Response) (io.ReadCloser, error) {
@@ -348,12 +603,25 @@ func getResponseBody(resp *http.Response) (io.ReadCloser, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, apierror.FromResponse(providerName, resp, parseError)
 	}
 
 	return resp.Body, nil
 }
 
+func parseError(body []byte) apierror.Detail {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return apierror.Detail{}
+	}
+
+	return apierror.Detail{
+		Code:    errResp.Error.Code,
+		Type:    errResp.Error.Type,
+		Message: errResp.Error.Message,
+	}
+}
+
 func unmarshalJSONResponse(resp *http.Response, v interface{}) error {
 	respBody, err := getResponseBody(resp)
 	if err != nil {
@@ -1,12 +1,71 @@
 package openai
 
+import (
+	"encoding/json"
+	"slices"
+)
+
 type ChatRequest struct {
 	Messages []ChatMessage `json:"messages"`
 	Model    string        `json:"model"`
+	Stream   bool          `json:"stream,omitempty"`
+
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema constrains a completion to JSON matching Schema.
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// StrictObject is an object schema as strict mode wants it, every property
+// is required and no other is allowed.
+func StrictObject(properties map[string]any) map[string]any {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	slices.Sort(required)
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type ChatResponse struct {
 	Choices []Choice `json:"choices"`
+	Usage   Usage    `json:"usage"`
+}
+
+type ChatStreamResponse struct {
+	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type StreamChoice struct {
+	Index        int         `json:"index"`
+	Delta        ChatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
 }
 
 type SSMLResponse struct {
@@ -33,13 +92,15 @@ type Choice struct {
 }
 
 type TTSRequest struct {
-	Model string `json:"model"`
-	Input string `json:"input"`
-	Voice string `json:"voice"`
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format,omitempty"`
 }
 
 type TranscriptResponse struct {
-	Text string `json:"text"`
+	Text     string  `json:"text"`
+	Duration float64 `json:"duration"`
 }
 
 type Status string
@@ -60,3 +121,13 @@ type Component struct {
 	Name   string `json:"name"`
 	Status Status `json:"status"`
 }
+
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
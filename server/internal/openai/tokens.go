@@ -0,0 +1,32 @@
+package openai
+
+import "unicode/utf8"
+
+// messageOverhead is what the chat format adds around every message.
+const messageOverhead = 4
+
+// CountTokens estimates the tokens a message takes in the prompt. It counts
+// about four characters per token for ASCII text and one token per
+// character otherwise, which is close enough to budget a context window
+// without shipping a tokenizer.
+func CountTokens(message ChatMessage) int {
+	var ascii, other int
+	for _, r := range message.Content {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+
+	return messageOverhead + (ascii+3)/4 + other
+}
+
+func CountAllTokens(messages []ChatMessage) int {
+	var total int
+	for _, message := range messages {
+		total += CountTokens(message)
+	}
+
+	return total
+}
@@ -0,0 +1,100 @@
+package persona
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const DEFAULT_PERSONA = "mai"
+
+// Persona is who the interviewer is. Style and greetings are keyed by
+// language, a language without a greeting uses the default greeting, voices
+// are keyed by speech provider and apply where the speech route sets none.
+type Persona struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Gender      string            `json:"gender"`
+	Description string            `json:"description"`
+	Style       map[string]string `json:"style"`
+	Voices      map[string]string `json:"voices"`
+	Greetings   map[string]string `json:"greetings"`
+}
+
+//go:embed personas.json
+var defaultCatalog []byte
+
+// Catalog keeps the personas in the order they were defined.
+type Catalog struct {
+	personas []Persona
+	byID     map[string]Persona
+}
+
+func DefaultCatalog() *Catalog {
+	catalog, err := parse(defaultCatalog)
+	if err != nil {
+		panic(err)
+	}
+
+	return catalog
+}
+
+// LoadCatalog reads a JSON list of personas replacing the defaults, it must
+// contain the default persona.
+func LoadCatalog(path string) (*Catalog, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(file)
+}
+
+func parse(file []byte) (*Catalog, error) {
+	var personas []Persona
+	if err := json.Unmarshal(file, &personas); err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{
+		personas: personas,
+		byID:     make(map[string]Persona, len(personas)),
+	}
+
+	for _, persona := range personas {
+		if persona.ID == "" || persona.Name == "" {
+			return nil, fmt.Errorf("persona needs an id and a name")
+		}
+		if _, ok := catalog.byID[persona.ID]; ok {
+			return nil, fmt.Errorf("duplicate persona %q", persona.ID)
+		}
+
+		catalog.byID[persona.ID] = persona
+	}
+
+	if _, ok := catalog.byID[DEFAULT_PERSONA]; !ok {
+		return nil, fmt.Errorf("default persona %q is missing", DEFAULT_PERSONA)
+	}
+
+	return catalog, nil
+}
+
+func (c *Catalog) List() []Persona {
+	return c.personas
+}
+
+func (c *Catalog) Get(id string) (Persona, bool) {
+	persona, ok := c.byID[id]
+	return persona, ok
+}
+
+// Resolve returns the persona of a stored session, sessions started before
+// personas existed, or whose persona was since removed, get the default.
+func (c *Catalog) Resolve(id string) Persona {
+	if persona, ok := c.byID[id]; ok {
+		return persona
+	}
+
+	return c.byID[DEFAULT_PERSONA]
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/madeindra/mock-interview/server/internal/anthropic"
+	"github.com/madeindra/mock-interview/server/internal/blob"
+	"github.com/madeindra/mock-interview/server/internal/config"
+	"github.com/madeindra/mock-interview/server/internal/elevenlab"
+	"github.com/madeindra/mock-interview/server/internal/fake"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/tts"
+)
+
+const (
+	PROVIDER_OPENAI            = "openai"
+	PROVIDER_OPENAI_COMPATIBLE = "openai-compatible"
+	PROVIDER_ANTHROPIC         = "anthropic"
+	PROVIDER_FAKE              = "fake"
+
+	BLOB_STORE_LOCAL = "local"
+	BLOB_STORE_S3    = "s3"
+)
+
+// ChatBackend is the part of openai.Client that generates the interviewer's
+// text, which is the part a deployment may want to serve from another vendor.
+type ChatBackend interface {
+	IsKeyValid(context.Context) (bool, error)
+	Chat(context.Context, []openai.ChatMessage) (string, error)
+	ChatStream(context.Context, []openai.ChatMessage, func(string) error) (string, error)
+	ChatJSON(context.Context, []openai.ChatMessage, openai.JSONSchema) (string, error)
+	SSML(context.Context, string) (string, error)
+}
+
+type Factory func(cfg config.AppConfig) (openai.Client, error)
+
+var registry = map[string]Factory{
+	PROVIDER_OPENAI:            newOpenAI,
+	PROVIDER_OPENAI_COMPATIBLE: newOpenAICompatible,
+	PROVIDER_ANTHROPIC:         newAnthropic,
+	PROVIDER_FAKE:              newFake,
+}
+
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SpeechProvider is the speech backend selected by cfg.SpeechProvider, the
+// fake one when the chat is fake and OpenAI otherwise if none is.
+func SpeechProvider(cfg config.AppConfig) string {
+	if cfg.SpeechProvider != "" {
+		return cfg.SpeechProvider
+	}
+
+	if cfg.LLMProvider == PROVIDER_FAKE {
+		return PROVIDER_FAKE
+	}
+
+	return PROVIDER_OPENAI
+}
+
+// NewClient builds the chat client selected by cfg.LLMProvider with the
+// speech backend selected by cfg.SpeechProvider.
+func NewClient(cfg config.AppConfig) (openai.Client, error) {
+	factory, ok := registry[cfg.LLMProvider]
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM provider %q, available: %v", cfg.LLMProvider, Names())
+	}
+
+	return factory(cfg)
+}
+
+// NewBlobStore builds the store selected by cfg.BlobStore for audio.
+func NewBlobStore(cfg config.AppConfig) (blob.Store, error) {
+	switch cfg.BlobStore {
+	case BLOB_STORE_LOCAL:
+		return blob.NewLocal(cfg.BlobDir)
+	case BLOB_STORE_S3:
+		return blob.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey,
+			blob.WithHTTPClient(httpClient(cfg)),
+		)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q, available: %s, %s", cfg.BlobStore, BLOB_STORE_LOCAL, BLOB_STORE_S3)
+	}
+}
+
+// NewTTSClient builds the alternative speech engine matching the speech
+// provider, the fake provider never reaches ElevenLabs.
+func NewTTSClient(cfg config.AppConfig) elevenlab.Client {
+	if SpeechProvider(cfg) == PROVIDER_FAKE {
+		return fake.NewElevenLab()
+	}
+
+	return elevenlab.NewElevenLab(cfg.TTSAPIKey,
+		elevenlab.WithHTTPClient(httpClient(cfg)),
+		elevenlab.WithTimeout(cfg.TTSTimeout),
+	)
+}
+
+// NewSpeechRouter routes speech synthesis between the speech provider, which
+// serves the openai route, and the alternative engine, which is only used
+// when its API key is set. The routes of the
+// language packs are applied over the defaults, and the configured routes
+// over both.
+func NewSpeechRouter(cfg config.AppConfig, ai openai.Client, el elevenlab.Client, packRoutes tts.Routes) (*tts.Router, error) {
+	routes := make(tts.Routes)
+	for language, voices := range tts.DefaultRoutes() {
+		routes[language] = voices
+	}
+	for language, voices := range packRoutes {
+		routes[language] = voices
+	}
+
+	if cfg.TTSRoutesPath != "" {
+		configured, err := tts.LoadRoutes(cfg.TTSRoutesPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for language, voices := range configured {
+			routes[language] = voices
+		}
+	}
+
+	router := tts.NewRouter(routes)
+	router.Register(tts.PROVIDER_OPENAI, tts.FromOpenAI(ai))
+	if cfg.TTSAPIKey != "" || SpeechProvider(cfg) == PROVIDER_FAKE {
+		router.Register(tts.PROVIDER_ELEVENLAB, tts.FromElevenLab(el))
+	}
+
+	return router, nil
+}
+
+// newSpeech builds the client transcribing answers and speaking replies.
+func newSpeech(cfg config.AppConfig) (openai.Client, error) {
+	switch provider := SpeechProvider(cfg); provider {
+	case PROVIDER_OPENAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("OpenAI API key is needed for speech provider %s", PROVIDER_OPENAI)
+		}
+
+		return openai.NewOpenAI(cfg.APIKey, speechOptions(cfg)...), nil
+	case PROVIDER_OPENAI_COMPATIBLE:
+		if cfg.SpeechBaseURL == "" {
+			return nil, fmt.Errorf("base URL is needed for speech provider %s", PROVIDER_OPENAI_COMPATIBLE)
+		}
+
+		return openai.NewOpenAICompatible(cfg.SpeechAPIKey, cfg.SpeechBaseURL, "", speechOptions(cfg)...), nil
+	case PROVIDER_FAKE:
+		return newFakeAI(cfg)
+	default:
+		return nil, fmt.Errorf("unknown speech provider %q, available: %s, %s, %s", provider, PROVIDER_OPENAI, PROVIDER_OPENAI_COMPATIBLE, PROVIDER_FAKE)
+	}
+}
+
+// withSpeech serves chat from the given backend, speech synthesis and
+// transcription from the speech provider.
+func withSpeech(cfg config.AppConfig, chat ChatBackend) (openai.Client, error) {
+	speech, err := newSpeech(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{
+		Client: speech,
+		chat:   chat,
+	}, nil
+}
+
+// client routes chat requests to the configured backend and everything else
+// to the embedded speech client.
+type client struct {
+	openai.Client
+	chat ChatBackend
+}
+
+func (c *client) IsKeyValid(ctx context.Context) (bool, error) {
+	return c.chat.IsKeyValid(ctx)
+}
+
+func (c *client) Chat(ctx context.Context, messages []openai.ChatMessage) (string, error) {
+	return c.chat.Chat(ctx, messages)
+}
+
+func (c *client) ChatStream(ctx context.Context, messages []openai.ChatMessage, onDelta func(string) error) (string, error) {
+	return c.chat.ChatStream(ctx, messages, onDelta)
+}
+
+func (c *client) ChatJSON(ctx context.Context, messages []openai.ChatMessage, schema openai.JSONSchema) (string, error) {
+	return c.chat.ChatJSON(ctx, messages, schema)
+}
+
+func (c *client) SSML(ctx context.Context, text string) (string, error) {
+	return c.chat.SSML(ctx, text)
+}
+
+func newOpenAI(cfg config.AppConfig) (openai.Client, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is needed")
+	}
+
+	chat := openai.NewOpenAI(cfg.APIKey, openAIOptions(cfg)...)
+	if SpeechProvider(cfg) == PROVIDER_OPENAI {
+		return chat, nil
+	}
+
+	return withSpeech(cfg, chat)
+}
+
+func newOpenAICompatible(cfg config.AppConfig) (openai.Client, error) {
+	if cfg.LLMBaseURL == "" || cfg.LLMModel == "" {
+		return nil, fmt.Errorf("base URL and model are needed for provider %s", PROVIDER_OPENAI_COMPATIBLE)
+	}
+
+	return withSpeech(cfg, openai.NewOpenAICompatible(cfg.LLMAPIKey, cfg.LLMBaseURL, cfg.LLMModel, openAIOptions(cfg)...))
+}
+
+func newAnthropic(cfg config.AppConfig) (openai.Client, error) {
+	if cfg.LLMAPIKey == "" {
+		return nil, fmt.Errorf("API key is needed for provider %s", PROVIDER_ANTHROPIC)
+	}
+
+	return withSpeech(cfg, anthropic.NewAnthropic(cfg.LLMAPIKey, cfg.LLMBaseURL, cfg.LLMModel,
+		anthropic.WithHTTPClient(httpClient(cfg)),
+		anthropic.WithTimeout(cfg.AITimeout),
+	))
+}
+
+func newFake(cfg config.AppConfig) (openai.Client, error) {
+	chat, err := newFakeAI(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if SpeechProvider(cfg) == PROVIDER_FAKE {
+		return chat, nil
+	}
+
+	return withSpeech(cfg, chat)
+}
+
+// newFakeAI replies from the script at cfg.FakeScriptPath, or the default
+// one.
+func newFakeAI(cfg config.AppConfig) (*fake.AI, error) {
+	if cfg.FakeScriptPath == "" {
+		return fake.NewAI(fake.DefaultScript()), nil
+	}
+
+	script, err := fake.LoadScript(cfg.FakeScriptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return fake.NewAI(script), nil
+}
+
+func openAIOptions(cfg config.AppConfig) []openai.Option {
+	return []openai.Option{
+		openai.WithHTTPClient(httpClient(cfg)),
+		openai.WithTimeout(cfg.AITimeout),
+	}
+}
+
+func speechOptions(cfg config.AppConfig) []openai.Option {
+	return append(openAIOptions(cfg), openai.WithSpeechModels(cfg.SpeechTranscriptionModel, cfg.SpeechTTSModel))
+}
+
+func httpClient(cfg config.AppConfig) *http.Client {
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient
+	}
+
+	return http.DefaultClient
+}
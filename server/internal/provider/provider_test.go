@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/madeindra/mock-interview/server/internal/config"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+)
+
+func TestNewClientSpeechKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.AppConfig
+		wantErr bool
+	}{
+		{"fake", config.AppConfig{LLMProvider: PROVIDER_FAKE}, false},
+		{"openai without key", config.AppConfig{LLMProvider: PROVIDER_OPENAI}, true},
+		{"anthropic with openai speech and no key", config.AppConfig{LLMProvider: PROVIDER_ANTHROPIC, LLMAPIKey: "key"}, true},
+		{"anthropic with openai speech", config.AppConfig{LLMProvider: PROVIDER_ANTHROPIC, LLMAPIKey: "key", APIKey: "key"}, false},
+		{"anthropic with compatible speech", config.AppConfig{LLMProvider: PROVIDER_ANTHROPIC, LLMAPIKey: "key", SpeechProvider: PROVIDER_OPENAI_COMPATIBLE, SpeechBaseURL: "http://localhost:8000/v1"}, false},
+		{"compatible speech without base URL", config.AppConfig{LLMProvider: PROVIDER_ANTHROPIC, LLMAPIKey: "key", SpeechProvider: PROVIDER_OPENAI_COMPATIBLE}, true},
+		{"openai chat with fake speech", config.AppConfig{LLMProvider: PROVIDER_OPENAI, APIKey: "key", SpeechProvider: PROVIDER_FAKE}, false},
+		{"unknown speech", config.AppConfig{LLMProvider: PROVIDER_FAKE, SpeechProvider: "carrier-pigeon"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewClient(test.cfg)
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// Recordings must reach the configured speech server and nothing else.
+func TestNewClientCompatibleSpeech(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+
+		if model := req.FormValue("model"); model != "faster-whisper" {
+			t.Errorf("got model %q, want the configured one", model)
+		}
+
+		json.NewEncoder(w).Encode(openai.TranscriptResponse{Text: "I am a backend engineer."})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.AppConfig{
+		LLMProvider:              PROVIDER_ANTHROPIC,
+		LLMAPIKey:                "key",
+		LLMBaseURL:               "http://chat.invalid",
+		SpeechProvider:           PROVIDER_OPENAI_COMPATIBLE,
+		SpeechBaseURL:            server.URL + "/v1",
+		SpeechTranscriptionModel: "faster-whisper",
+		HTTPClient:               server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transcript, err := client.Transcribe(context.Background(), io.NopCloser(strings.NewReader("audio")), "answer.webm", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transcript.Text != "I am a backend engineer." {
+		t.Errorf("got transcript %q, want the speech server's", transcript.Text)
+	}
+
+	if len(paths) != 1 || paths[0] != "/v1/audio/transcriptions" {
+		t.Errorf("got requests to %v, want one to /v1/audio/transcriptions", paths)
+	}
+}
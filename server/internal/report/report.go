@@ -0,0 +1,267 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/madeindra/mock-interview/server/internal/data"
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+)
+
+const (
+	RECOMMENDATION_STRONG_HIRE  = "strong_hire"
+	RECOMMENDATION_HIRE         = "hire"
+	RECOMMENDATION_LEAN_HIRE    = "lean_hire"
+	RECOMMENDATION_LEAN_NO_HIRE = "lean_no_hire"
+	RECOMMENDATION_NO_HIRE      = "no_hire"
+
+	MIN_SCORE = 1
+	MAX_SCORE = 5
+
+	schemaName = "interview_report"
+)
+
+var recommendations = []string{
+	RECOMMENDATION_STRONG_HIRE,
+	RECOMMENDATION_HIRE,
+	RECOMMENDATION_LEAN_HIRE,
+	RECOMMENDATION_LEAN_NO_HIRE,
+	RECOMMENDATION_NO_HIRE,
+}
+
+// Report is the structured evaluation of an interview.
+type Report struct {
+	Recommendation string       `json:"recommendation"`
+	Summary        string       `json:"summary"`
+	Skills         []SkillScore `json:"skills"`
+	Strengths      []string     `json:"strengths"`
+	Improvements   []string     `json:"improvements"`
+	Evidence       []Evidence   `json:"evidence"`
+}
+
+type SkillScore struct {
+	Skill   string `json:"skill"`
+	Score   int    `json:"score"`
+	Comment string `json:"comment"`
+}
+
+// Evidence ties an observation to the chat entry it was made on.
+type Evidence struct {
+	EntryID     string `json:"entryId"`
+	Observation string `json:"observation"`
+}
+
+// Generate evaluates the interview held in the entries, scoring the given
+// skills, with every text in the pack's language.
+func Generate(ctx context.Context, ai openai.Client, pack *language.Pack, role string, skills []string, entries []data.Entry) (*Report, error) {
+	var transcript strings.Builder
+	var answerIDs []string
+	for _, entry := range entries {
+		speaker := "Interviewer"
+		switch openai.Role(entry.Role) {
+		case openai.ROLE_SYSTEM:
+			continue
+		case openai.ROLE_USER:
+			speaker = "Candidate"
+			answerIDs = append(answerIDs, entry.ID)
+		}
+
+		fmt.Fprintf(&transcript, "[%s] %s: %s\n", entry.ID, speaker, entry.Text)
+	}
+
+	if len(answerIDs) == 0 {
+		return nil, fmt.Errorf("the interview has no answers to evaluate")
+	}
+
+	skills = uniqueSkills(skills)
+
+	schema, err := json.Marshal(buildSchema(skills, answerIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	skillKeys := make([]string, len(skills))
+	for i, skill := range skills {
+		skillKeys[i] = skillKey(i) + " (" + skill + ")"
+	}
+
+	messages := []openai.ChatMessage{
+		{
+			Role:    openai.ROLE_SYSTEM,
+			Content: openai.GetReportPrompt(),
+		},
+		{
+			Role: openai.ROLE_SYSTEM,
+			Content: fmt.Sprintf("The interview was for the %s role. Skills to score: %s. Write every text of the report in %s.",
+				role, strings.Join(skillKeys, ", "), pack.Name),
+		},
+		{
+			Role:    openai.ROLE_USER,
+			Content: transcript.String(),
+		},
+	}
+
+	content, err := ai.ChatJSON(ctx, messages, openai.JSONSchema{
+		Name:   schemaName,
+		Strict: true,
+		Schema: schema,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Report
+		// Skills is keyed by skillKey when skills were given, a list otherwise
+		Skills json.RawMessage `json:"skills"`
+	}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %w", err)
+	}
+
+	report := result.Report
+	report.Skills, err = parseSkills(result.Skills, skills)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := report.validate(answerIDs); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// parseSkills returns a score for every given skill, in the order they were
+// given, or every skill the model scored when none were given.
+func parseSkills(content json.RawMessage, skills []string) ([]SkillScore, error) {
+	if len(skills) == 0 {
+		var scores []SkillScore
+		if err := json.Unmarshal(content, &scores); err != nil {
+			return nil, fmt.Errorf("failed to parse skill scores: %w", err)
+		}
+
+		return scores, nil
+	}
+
+	var scored map[string]struct {
+		Score   int    `json:"score"`
+		Comment string `json:"comment"`
+	}
+	if err := json.Unmarshal(content, &scored); err != nil {
+		return nil, fmt.Errorf("failed to parse skill scores: %w", err)
+	}
+
+	scores := make([]SkillScore, len(skills))
+	for i, skill := range skills {
+		value, ok := scored[skillKey(i)]
+		if !ok {
+			return nil, fmt.Errorf("skill %q was not scored", skill)
+		}
+
+		scores[i] = SkillScore{Skill: skill, Score: value.Score, Comment: value.Comment}
+	}
+
+	return scores, nil
+}
+
+// skillKey names the schema property of the i-th skill. Skills are free
+// text, which not every provider accepts as a property name.
+func skillKey(i int) string {
+	return "skill_" + strconv.Itoa(i)
+}
+
+// uniqueSkills drops blank and repeated skills so each is scored once.
+func uniqueSkills(skills []string) []string {
+	var unique []string
+	for _, skill := range skills {
+		skill = strings.TrimSpace(skill)
+		if skill != "" && !slices.Contains(unique, skill) {
+			unique = append(unique, skill)
+		}
+	}
+
+	return unique
+}
+
+// validate checks what the schema cannot guarantee with every provider,
+// keeping only evidence on the candidate's answers.
+func (r *Report) validate(answerIDs []string) error {
+	if !slices.Contains(recommendations, r.Recommendation) {
+		return fmt.Errorf("unknown recommendation %q", r.Recommendation)
+	}
+
+	for _, score := range r.Skills {
+		if score.Score < MIN_SCORE || score.Score > MAX_SCORE {
+			return fmt.Errorf("score %d of skill %q is out of range", score.Score, score.Skill)
+		}
+	}
+
+	var evidence []Evidence
+	for _, item := range r.Evidence {
+		if slices.Contains(answerIDs, item.EntryID) {
+			evidence = append(evidence, item)
+		}
+	}
+	r.Evidence = evidence
+
+	return nil
+}
+
+// buildSchema has a property per skill so none of them can be left out, and
+// limits cited entries to the ones of the interview.
+func buildSchema(skills, answerIDs []string) map[string]any {
+	scores := make([]int, 0, MAX_SCORE)
+	for score := MIN_SCORE; score <= MAX_SCORE; score++ {
+		scores = append(scores, score)
+	}
+
+	skillScores := map[string]any{
+		"type": "array",
+		"items": openai.StrictObject(map[string]any{
+			"skill":   map[string]any{"type": "string"},
+			"score":   map[string]any{"type": "integer", "enum": scores},
+			"comment": map[string]any{"type": "string"},
+		}),
+	}
+
+	if len(skills) > 0 {
+		properties := make(map[string]any, len(skills))
+		for i, skill := range skills {
+			property := openai.StrictObject(map[string]any{
+				"score":   map[string]any{"type": "integer", "enum": scores},
+				"comment": map[string]any{"type": "string"},
+			})
+			property["description"] = skill
+
+			properties[skillKey(i)] = property
+		}
+
+		skillScores = openai.StrictObject(properties)
+	}
+
+	texts := map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string"},
+	}
+
+	return openai.StrictObject(map[string]any{
+		"recommendation": map[string]any{"type": "string", "enum": recommendations},
+		"summary":        map[string]any{"type": "string"},
+		"skills":         skillScores,
+		"strengths":      texts,
+		"improvements":   texts,
+		"evidence": map[string]any{
+			"type": "array",
+			"items": openai.StrictObject(map[string]any{
+				"entryId":     map[string]any{"type": "string", "enum": answerIDs},
+				"observation": map[string]any{"type": "string"},
+			}),
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+// the property names every provider accepts in a tool's input schema
+var propertyName = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+func TestBuildSchemaPropertyNames(t *testing.T) {
+	schema := buildSchema([]string{"System Design", "C++", "Go/gRPC"}, []string{"answer"})
+
+	var check func(path string, node map[string]any)
+	check = func(path string, node map[string]any) {
+		properties, _ := node["properties"].(map[string]any)
+		for name, property := range properties {
+			if !propertyName.MatchString(name) {
+				t.Errorf("%s: property %q is not a valid name", path, name)
+			}
+
+			if child, ok := property.(map[string]any); ok {
+				check(path+"."+name, child)
+			}
+		}
+
+		if items, ok := node["items"].(map[string]any); ok {
+			check(path+"[]", items)
+		}
+	}
+
+	check("report", schema)
+}
+
+func TestParseSkills(t *testing.T) {
+	skills := []string{"System Design", "Go"}
+
+	scores, err := parseSkills(json.RawMessage(`{"skill_1": {"score": 4, "comment": "idiomatic"}, "skill_0": {"score": 2, "comment": "vague"}}`), skills)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SkillScore{{Skill: "System Design", Score: 2, Comment: "vague"}, {Skill: "Go", Score: 4, Comment: "idiomatic"}}
+	if len(scores) != len(want) || scores[0] != want[0] || scores[1] != want[1] {
+		t.Errorf("got %+v, want %+v", scores, want)
+	}
+
+	if _, err := parseSkills(json.RawMessage(`{"skill_0": {"score": 2, "comment": "vague"}}`), skills); err == nil {
+		t.Error("got no error for a skill left out")
+	}
+}
@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+)
+
+type State string
+
+const (
+	STATE_CLOSED    State = "closed"
+	STATE_OPEN      State = "open"
+	STATE_HALF_OPEN State = "half_open"
+)
+
+// Breaker stops calling an upstream after consecutive failures. Once the
+// cooldown has passed a single probe call is let through, its outcome
+// decides whether the breaker closes again or stays open.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     STATE_CLOSED,
+	}
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == STATE_OPEN && time.Since(b.openedAt) >= b.cooldown {
+		return STATE_HALF_OPEN
+	}
+
+	return b.state
+}
+
+// Allow returns an error when the call must not be attempted.
+func (b *Breaker) Allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == STATE_OPEN {
+		remaining := b.cooldown - time.Since(b.openedAt)
+		if remaining > 0 {
+			return apierror.Unavailable(b.name, remaining)
+		}
+
+		b.state = STATE_HALF_OPEN
+	}
+
+	if b.state == STATE_HALF_OPEN {
+		if b.probing {
+			return apierror.Unavailable(b.name, b.cooldown)
+		}
+
+		b.probing = true
+	}
+
+	return nil
+}
+
+// Record reports the outcome of an allowed call. Only upstream failures
+// count, a rejected request or a cancelled context says nothing about
+// the upstream's health.
+func (b *Breaker) Record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbing := b.probing
+	b.probing = false
+
+	switch {
+	case err == nil:
+		b.state = STATE_CLOSED
+		b.failures = 0
+	case isUpstreamFailure(err):
+		b.failures++
+
+		if wasProbing || b.failures >= b.threshold {
+			b.state = STATE_OPEN
+			b.openedAt = time.Now()
+		}
+	}
+}
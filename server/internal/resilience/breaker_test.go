@@ -0,0 +1,83 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+)
+
+var errServer = &apierror.Error{Provider: "test", Kind: apierror.KIND_SERVER}
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	breaker := NewBreaker("test", 2, cooldown)
+
+	// failures that say nothing about the upstream do not count
+	breaker.Record(&apierror.Error{Kind: apierror.KIND_INVALID_REQUEST})
+	breaker.Record(context.Canceled)
+
+	breaker.Record(errServer)
+	if breaker.State() != STATE_CLOSED || breaker.Allow() != nil {
+		t.Fatalf("got %s after one failure, want closed", breaker.State())
+	}
+
+	breaker.Record(errServer)
+	if breaker.State() != STATE_OPEN {
+		t.Fatalf("got %s after two failures, want open", breaker.State())
+	}
+
+	if err := breaker.Allow(); apierror.KindOf(err) != apierror.KIND_UNAVAILABLE {
+		t.Fatalf("got %v while open, want unavailable", err)
+	}
+
+	time.Sleep(cooldown)
+
+	if breaker.State() != STATE_HALF_OPEN {
+		t.Fatalf("got %s after the cooldown, want half open", breaker.State())
+	}
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("got %v for the probe, want it allowed", err)
+	}
+
+	if err := breaker.Allow(); apierror.KindOf(err) != apierror.KIND_UNAVAILABLE {
+		t.Fatalf("got %v during the probe, want unavailable", err)
+	}
+
+	// a failed probe opens the breaker again at once
+	breaker.Record(errServer)
+	if breaker.State() != STATE_OPEN {
+		t.Fatalf("got %s after a failed probe, want open", breaker.State())
+	}
+
+	time.Sleep(cooldown)
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("got %v for the second probe, want it allowed", err)
+	}
+
+	breaker.Record(nil)
+	if breaker.State() != STATE_CLOSED || breaker.Allow() != nil {
+		t.Fatalf("got %s after a successful probe, want closed", breaker.State())
+	}
+
+	// the failure count starts over once closed
+	breaker.Record(errServer)
+	if breaker.State() != STATE_CLOSED {
+		t.Errorf("got %s after one new failure, want closed", breaker.State())
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	breaker := NewBreaker("test", 0, time.Minute)
+
+	for range 10 {
+		breaker.Record(errServer)
+	}
+
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("got %v, want a disabled breaker to allow every call", err)
+	}
+}
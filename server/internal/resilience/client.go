@@ -0,0 +1,141 @@
+package resilience
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/madeindra/mock-interview/server/internal/elevenlab"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+)
+
+// Client retries the idempotent calls of an openai.Client behind a circuit
+// breaker. IsKeyValid and Status are diagnostics and pass straight through.
+type Client struct {
+	openai.Client
+	policy  Policy
+	breaker *Breaker
+}
+
+func NewClient(client openai.Client, policy Policy, breaker *Breaker) *Client {
+	return &Client{
+		Client:  client,
+		policy:  policy,
+		breaker: breaker,
+	}
+}
+
+func (c *Client) Chat(ctx context.Context, messages []openai.ChatMessage) (string, error) {
+	var text string
+	err := c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		text, err = c.Client.Chat(ctx, messages)
+		return err
+	})
+
+	return text, err
+}
+
+// ChatStream only retries until the first token has been forwarded, after
+// that the caller has already seen part of the answer.
+func (c *Client) ChatStream(ctx context.Context, messages []openai.ChatMessage, onDelta func(string) error) (string, error) {
+	var text string
+	var streamed bool
+
+	err := c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		text, err = c.Client.ChatStream(ctx, messages, func(delta string) error {
+			streamed = true
+			return onDelta(delta)
+		})
+
+		if err != nil && streamed {
+			return permanentError{err}
+		}
+
+		return err
+	})
+
+	return text, err
+}
+
+func (c *Client) ChatJSON(ctx context.Context, messages []openai.ChatMessage, schema openai.JSONSchema) (string, error) {
+	var text string
+	err := c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		text, err = c.Client.ChatJSON(ctx, messages, schema)
+		return err
+	})
+
+	return text, err
+}
+
+func (c *Client) SSML(ctx context.Context, text string) (string, error) {
+	var ssml string
+	err := c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		ssml, err = c.Client.SSML(ctx, text)
+		return err
+	})
+
+	return ssml, err
+}
+
+func (c *Client) TextToSpeech(ctx context.Context, input string, opts openai.SpeechOptions) (io.ReadCloser, error) {
+	var speech io.ReadCloser
+	err := c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		speech, err = c.Client.TextToSpeech(ctx, input, opts)
+		return err
+	})
+
+	return speech, err
+}
+
+// Transcribe buffers the upload so every attempt can send it again.
+func (c *Client) Transcribe(ctx context.Context, file io.ReadCloser, filename, language string) (openai.TranscriptResponse, error) {
+	if file == nil {
+		return c.Client.Transcribe(ctx, file, filename, language)
+	}
+
+	audio, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return openai.TranscriptResponse{}, err
+	}
+
+	var transcript openai.TranscriptResponse
+	err = c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		transcript, err = c.Client.Transcribe(ctx, io.NopCloser(bytes.NewReader(audio)), filename, language)
+		return err
+	})
+
+	return transcript, err
+}
+
+// TTSClient does the same for the alternative speech engine.
+type TTSClient struct {
+	elevenlab.Client
+	policy  Policy
+	breaker *Breaker
+}
+
+func NewTTSClient(client elevenlab.Client, policy Policy, breaker *Breaker) *TTSClient {
+	return &TTSClient{
+		Client:  client,
+		policy:  policy,
+		breaker: breaker,
+	}
+}
+
+func (c *TTSClient) TextToSpeech(ctx context.Context, input string, opts elevenlab.SpeechOptions) (io.ReadCloser, error) {
+	var speech io.ReadCloser
+	err := c.policy.Do(ctx, c.breaker, func() error {
+		var err error
+		speech, err = c.Client.TextToSpeech(ctx, input, opts)
+		return err
+	})
+
+	return speech, err
+}
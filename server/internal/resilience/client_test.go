@@ -0,0 +1,67 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/openai"
+)
+
+// streamClient fails every stream, after sending the given deltas.
+type streamClient struct {
+	openai.Client
+	deltas []string
+	calls  int
+}
+
+func (c *streamClient) ChatStream(ctx context.Context, messages []openai.ChatMessage, onDelta func(string) error) (string, error) {
+	c.calls++
+
+	for _, delta := range c.deltas {
+		if err := onDelta(delta); err != nil {
+			return "", err
+		}
+	}
+
+	return "", errServer
+}
+
+func TestClientChatStream(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	tests := []struct {
+		name   string
+		deltas []string
+		want   int
+	}{
+		{"fails before the first token", nil, 3},
+		{"fails after the first token", []string{"Hello"}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upstream := &streamClient{deltas: test.deltas}
+			client := NewClient(upstream, policy, NewBreaker("test", 0, 0))
+
+			var forwarded []string
+			_, err := client.ChatStream(context.Background(), nil, func(delta string) error {
+				forwarded = append(forwarded, delta)
+				return nil
+			})
+
+			if !errors.Is(err, errServer) {
+				t.Errorf("got %v, want the upstream error", err)
+			}
+
+			if upstream.calls != test.want {
+				t.Errorf("got %d calls, want %d", upstream.calls, test.want)
+			}
+
+			if len(forwarded) != len(test.deltas) {
+				t.Errorf("got %v forwarded, want %v once", forwarded, test.deltas)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+)
+
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Do calls fn until it succeeds, fails with an error that is not worth
+// retrying, or runs out of attempts, waiting between attempts with
+// jittered exponential backoff or for as long as the upstream asked. An
+// upstream asking for more than MaxDelay gets its error returned instead.
+func (p Policy) Do(ctx context.Context, breaker *Breaker, fn func() error) error {
+	attempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err := breaker.Allow(); err != nil {
+			return err
+		}
+
+		err = fn()
+		breaker.Record(err)
+
+		if err == nil || attempt >= attempts || !isRetryable(ctx, err) {
+			return err
+		}
+
+		delay, ok := p.backoff(attempt, err)
+		if !ok {
+			return err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff is the wait before the next attempt, false when the upstream
+// asked to wait longer than MaxDelay.
+func (p Policy) backoff(attempt int, err error) (time.Duration, bool) {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// equal jitter keeps at least half of the delay
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		if apiErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+
+		delay = apiErr.RetryAfter
+	}
+
+	return delay, true
+}
+
+// permanentError marks a failure that must not be retried even though its
+// cause normally would be.
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error {
+	return e.error
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var permanent permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	return isUpstreamFailure(err)
+}
+
+func isUpstreamFailure(err error) bool {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable() && apiErr.Kind != apierror.KIND_UNAVAILABLE
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,103 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+)
+
+func TestBackoffBounds(t *testing.T) {
+	policy := Policy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	err := &apierror.Error{Kind: apierror.KIND_SERVER}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		want := min(policy.BaseDelay<<(attempt-1), policy.MaxDelay)
+
+		for range 20 {
+			delay, ok := policy.backoff(attempt, err)
+			if !ok {
+				t.Fatalf("attempt %d: got no retry, want one", attempt)
+			}
+
+			if delay < want/2 || delay > want {
+				t.Fatalf("attempt %d: got delay %v, want between %v and %v", attempt, delay, want/2, want)
+			}
+		}
+	}
+
+	// a shift past the width of a duration must not wrap around
+	if delay, _ := policy.backoff(100, err); delay > policy.MaxDelay || delay < policy.MaxDelay/2 {
+		t.Errorf("attempt 100: got delay %v, want at most %v", delay, policy.MaxDelay)
+	}
+}
+
+func TestBackoffRetryAfter(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}
+
+	delay, ok := policy.backoff(1, &apierror.Error{Kind: apierror.KIND_RATE_LIMIT, RetryAfter: 500 * time.Millisecond})
+	if !ok || delay != 500*time.Millisecond {
+		t.Errorf("got %v %v, want the 500ms asked for", delay, ok)
+	}
+
+	if delay, ok := policy.backoff(1, &apierror.Error{Kind: apierror.KIND_RATE_LIMIT, RetryAfter: 10 * time.Minute}); ok {
+		t.Errorf("got a retry after %v, want none beyond MaxDelay", delay)
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 1},
+		{"server error", &apierror.Error{Kind: apierror.KIND_SERVER}, 3},
+		{"rate limit", &apierror.Error{Kind: apierror.KIND_RATE_LIMIT}, 3},
+		{"rate limit asking too long", &apierror.Error{Kind: apierror.KIND_RATE_LIMIT, RetryAfter: 10 * time.Minute}, 1},
+		{"invalid request", &apierror.Error{Kind: apierror.KIND_INVALID_REQUEST}, 1},
+		{"quota", &apierror.Error{Kind: apierror.KIND_QUOTA}, 1},
+		{"cancelled", context.Canceled, 1},
+		{"permanent", permanentError{&apierror.Error{Kind: apierror.KIND_SERVER}}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			err := policy.Do(context.Background(), NewBreaker("test", 0, 0), func() error {
+				calls++
+				return test.err
+			})
+
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+
+			if calls != test.want {
+				t.Errorf("got %d calls, want %d", calls, test.want)
+			}
+		})
+	}
+}
+
+func TestDoStopsAtDeadline(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	policy.Do(ctx, NewBreaker("test", 0, 0), func() error {
+		calls++
+		return &apierror.Error{Kind: apierror.KIND_SERVER}
+	})
+
+	if calls != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("got %d calls in %v, want 1 without waiting past the deadline", calls, time.Since(start))
+	}
+}
@@ -0,0 +1,184 @@
+package rubric
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/madeindra/mock-interview/server/internal/language"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+)
+
+const (
+	MIN_SCORE = 1
+	MAX_SCORE = 5
+
+	schemaName = "answer_score"
+)
+
+// Criterion is one aspect every answer is scored on.
+type Criterion struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Score is the evaluation of a single answer, with the criteria in the
+// order of the rubric.
+type Score struct {
+	Criteria []CriterionScore `json:"criteria"`
+	Feedback string           `json:"feedback"`
+}
+
+type CriterionScore struct {
+	Criterion string `json:"criterion"`
+	Score     int    `json:"score"`
+	Comment   string `json:"comment"`
+}
+
+//go:embed rubric.json
+var defaultRubric []byte
+
+type Rubric struct {
+	criteria []Criterion
+}
+
+func DefaultRubric() *Rubric {
+	rubric, err := parse(defaultRubric)
+	if err != nil {
+		panic(err)
+	}
+
+	return rubric
+}
+
+// Load reads a JSON list of criteria replacing the defaults.
+func Load(path string) (*Rubric, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(file)
+}
+
+func parse(file []byte) (*Rubric, error) {
+	var criteria []Criterion
+	if err := json.Unmarshal(file, &criteria); err != nil {
+		return nil, err
+	}
+
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("rubric has no criteria")
+	}
+
+	seen := make(map[string]bool, len(criteria))
+	for _, criterion := range criteria {
+		if criterion.ID == "" || criterion.Description == "" {
+			return nil, fmt.Errorf("criterion needs an id and a description")
+		}
+		if seen[criterion.ID] {
+			return nil, fmt.Errorf("duplicate criterion %q", criterion.ID)
+		}
+
+		seen[criterion.ID] = true
+	}
+
+	return &Rubric{criteria: criteria}, nil
+}
+
+// Score evaluates the answer to the question on every criterion, with the
+// comments written in the pack's language.
+func (r *Rubric) Score(ctx context.Context, ai openai.Client, pack *language.Pack, question, answer string) (*Score, error) {
+	schema, err := json.Marshal(r.schema())
+	if err != nil {
+		return nil, err
+	}
+
+	var criteria strings.Builder
+	for _, criterion := range r.criteria {
+		fmt.Fprintf(&criteria, "- %s: %s\n", criterion.ID, criterion.Description)
+	}
+
+	messages := []openai.ChatMessage{
+		{
+			Role:    openai.ROLE_SYSTEM,
+			Content: openai.GetScorePrompt(),
+		},
+		{
+			Role:    openai.ROLE_SYSTEM,
+			Content: fmt.Sprintf("Criteria:\n%sWrite the comments and feedback in %s.", criteria.String(), pack.Name),
+		},
+		{
+			Role:    openai.ROLE_USER,
+			Content: fmt.Sprintf("Question: %s\n\nAnswer: %s", question, answer),
+		},
+	}
+
+	content, err := ai.ChatJSON(ctx, messages, openai.JSONSchema{
+		Name:   schemaName,
+		Strict: true,
+		Schema: schema,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Scores map[string]struct {
+			Score   int    `json:"score"`
+			Comment string `json:"comment"`
+		} `json:"scores"`
+		Feedback string `json:"feedback"`
+	}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse score: %w", err)
+	}
+
+	score := &Score{
+		Criteria: make([]CriterionScore, len(r.criteria)),
+		Feedback: result.Feedback,
+	}
+
+	for i, criterion := range r.criteria {
+		value, ok := result.Scores[criterion.ID]
+		if !ok {
+			return nil, fmt.Errorf("criterion %q was not scored", criterion.ID)
+		}
+		if value.Score < MIN_SCORE || value.Score > MAX_SCORE {
+			return nil, fmt.Errorf("score %d of criterion %q is out of range", value.Score, criterion.ID)
+		}
+
+		score.Criteria[i] = CriterionScore{
+			Criterion: criterion.ID,
+			Score:     value.Score,
+			Comment:   value.Comment,
+		}
+	}
+
+	return score, nil
+}
+
+// schema has a property per criterion so none of them can be left out.
+func (r *Rubric) schema() map[string]any {
+	scores := make([]int, 0, MAX_SCORE)
+	for score := MIN_SCORE; score <= MAX_SCORE; score++ {
+		scores = append(scores, score)
+	}
+
+	criteria := make(map[string]any, len(r.criteria))
+	for _, criterion := range r.criteria {
+		criteria[criterion.ID] = openai.StrictObject(map[string]any{
+			"score":   map[string]any{"type": "integer", "enum": scores},
+			"comment": map[string]any{"type": "string"},
+		})
+	}
+
+	return openai.StrictObject(map[string]any{
+		"scores":   openai.StrictObject(criteria),
+		"feedback": map[string]any{"type": "string"},
+	})
+}
@@ -0,0 +1,55 @@
+package session
+
+import "time"
+
+// Limits of zero are not enforced.
+type Limits struct {
+	Duration     time.Duration
+	MaxQuestions int
+}
+
+// Progress is how far an interview is into its limits. The remaining values
+// are nil for limits that are not set.
+type Progress struct {
+	Elapsed            time.Duration
+	Remaining          *time.Duration
+	Answers            int
+	RemainingQuestions *int
+}
+
+// Elapsed is the interview time since the start, leaving out the time it
+// was paused, including the pause still going on if pausedAt is set.
+func Elapsed(startedAt time.Time, paused time.Duration, pausedAt *time.Time, now time.Time) time.Duration {
+	if startedAt.IsZero() {
+		return 0
+	}
+
+	if pausedAt != nil {
+		now = *pausedAt
+	}
+
+	return max(now.Sub(startedAt)-paused, 0)
+}
+
+// Measure reports the progress of an interview that has been running for
+// elapsed and has that many answers.
+func (l Limits) Measure(elapsed time.Duration, answers int) Progress {
+	progress := Progress{Elapsed: elapsed, Answers: answers}
+
+	if l.Duration > 0 {
+		remaining := max(l.Duration-elapsed, 0)
+		progress.Remaining = &remaining
+	}
+
+	if l.MaxQuestions > 0 {
+		remaining := max(l.MaxQuestions-answers, 0)
+		progress.RemainingQuestions = &remaining
+	}
+
+	return progress
+}
+
+// Done reports whether a limit has been hit and the interview should close.
+func (p Progress) Done() bool {
+	return (p.Remaining != nil && *p.Remaining == 0) || (p.RemainingQuestions != nil && *p.RemainingQuestions == 0)
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	STATUS_ACTIVE  = "active"
+	STATUS_PAUSED  = "paused"
+	STATUS_ENDED   = "ended"
+	STATUS_EXPIRED = "expired"
+
+	CODE_SESSION_ACTIVE  = "session_active"
+	CODE_SESSION_PAUSED  = "session_paused"
+	CODE_SESSION_ENDED   = "session_ended"
+	CODE_SESSION_EXPIRED = "session_expired"
+)
+
+// transitions lists where a session can go from each status, ended and
+// expired sessions are final.
+var transitions = map[string][]string{
+	STATUS_ACTIVE: {STATUS_PAUSED, STATUS_ENDED, STATUS_EXPIRED},
+	STATUS_PAUSED: {STATUS_ACTIVE, STATUS_ENDED, STATUS_EXPIRED},
+}
+
+var codes = map[string]string{
+	STATUS_ACTIVE:  CODE_SESSION_ACTIVE,
+	STATUS_PAUSED:  CODE_SESSION_PAUSED,
+	STATUS_ENDED:   CODE_SESSION_ENDED,
+	STATUS_EXPIRED: CODE_SESSION_EXPIRED,
+}
+
+var messages = map[string]string{
+	CODE_SESSION_ACTIVE:  "this interview is already in progress",
+	CODE_SESSION_PAUSED:  "this interview is paused, resume it to continue",
+	CODE_SESSION_ENDED:   "this interview has ended",
+	CODE_SESSION_EXPIRED: "this interview has expired",
+}
+
+// Error is returned when a session is not in a status that allows the
+// request, Code is machine-readable and names the current status.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string {
+	return messages[e.Code]
+}
+
+// Transition checks that a session in status can move to the next one.
+func Transition(status, next string) error {
+	if slices.Contains(transitions[status], next) {
+		return nil
+	}
+
+	return &Error{Code: codes[status]}
+}
+
+// StatusError is the error naming status, for a request that found the
+// session in it.
+func StatusError(status string) error {
+	return &Error{Code: codes[status]}
+}
+
+// Active checks that a session in status takes answers.
+func Active(status string) error {
+	if status == STATUS_ACTIVE {
+		return nil
+	}
+
+	return &Error{Code: codes[status]}
+}
+
+// From lists the statuses a session can move to next from.
+func From(next string) []string {
+	var statuses []string
+	for status, targets := range transitions {
+		if slices.Contains(targets, next) {
+			statuses = append(statuses, status)
+		}
+	}
+	slices.Sort(statuses)
+
+	return statuses
+}
+
+// Expired reports whether a session started at startedAt has outlived ttl,
+// a ttl of zero never expires.
+func Expired(startedAt time.Time, ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && !startedAt.IsZero() && now.Sub(startedAt) > ttl
+}
@@ -0,0 +1,158 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/madeindra/mock-interview/server/internal/elevenlab"
+	"github.com/madeindra/mock-interview/server/internal/openai"
+)
+
+const (
+	PROVIDER_OPENAI    = "openai"
+	PROVIDER_ELEVENLAB = "elevenlab"
+
+	// DEFAULT_ROUTE is used for languages without a route of their own
+	DEFAULT_ROUTE = "*"
+)
+
+// Voice is one step of a route, empty fields use the provider's defaults.
+type Voice struct {
+	Provider string `json:"provider"`
+	Voice    string `json:"voice,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Format   string `json:"format,omitempty"`
+}
+
+// Routes maps a language to the voices to try, in order.
+type Routes map[string][]Voice
+
+// defaultRoutes apply to languages whose pack has no route of its own
+var defaultRoutes = Routes{
+	DEFAULT_ROUTE: {
+		{Provider: PROVIDER_ELEVENLAB},
+	},
+}
+
+func DefaultRoutes() Routes {
+	return defaultRoutes
+}
+
+// LoadRoutes reads routes from a JSON file, they replace the defaults.
+func LoadRoutes(path string) (Routes, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes Routes
+	if err := json.Unmarshal(file, &routes); err != nil {
+		return nil, err
+	}
+
+	if err := routes.Validate(); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+func (routes Routes) Validate() error {
+	for language, voices := range routes {
+		for _, voice := range voices {
+			if voice.Provider != PROVIDER_OPENAI && voice.Provider != PROVIDER_ELEVENLAB {
+				return fmt.Errorf("unknown speech provider %q for language %q", voice.Provider, language)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Synthesizer speaks the text with the given voice.
+type Synthesizer func(ctx context.Context, text string, voice Voice) (io.ReadCloser, error)
+
+func FromOpenAI(client openai.Client) Synthesizer {
+	return func(ctx context.Context, text string, voice Voice) (io.ReadCloser, error) {
+		return client.TextToSpeech(ctx, text, openai.SpeechOptions{
+			Voice:  voice.Voice,
+			Model:  voice.Model,
+			Format: voice.Format,
+		})
+	}
+}
+
+func FromElevenLab(client elevenlab.Client) Synthesizer {
+	return func(ctx context.Context, text string, voice Voice) (io.ReadCloser, error) {
+		return client.TextToSpeech(ctx, text, elevenlab.SpeechOptions{
+			Voice:  voice.Voice,
+			Model:  voice.Model,
+			Format: voice.Format,
+		})
+	}
+}
+
+// Router picks the speech provider for a language, falling through to the
+// next voice of the route when a provider fails.
+type Router struct {
+	routes    Routes
+	providers map[string]Synthesizer
+}
+
+func NewRouter(routes Routes) *Router {
+	return &Router{
+		routes:    routes,
+		providers: make(map[string]Synthesizer),
+	}
+}
+
+// Register makes a provider available, voices of providers that are not
+// registered are skipped.
+func (r *Router) Register(provider string, synthesizer Synthesizer) {
+	r.providers[provider] = synthesizer
+}
+
+// Route returns the voices tried for the language.
+func (r *Router) Route(language string) []Voice {
+	if voices, ok := r.routes[language]; ok {
+		return voices
+	}
+
+	return r.routes[DEFAULT_ROUTE]
+}
+
+// Synthesize returns the audio of the first voice that succeeds, nil when
+// no provider of the route is available, or the last error when all failed.
+// Voices, keyed by provider, are used for route steps without a voice, a
+// voice configured on the route always wins.
+func (r *Router) Synthesize(ctx context.Context, language, text string, voices map[string]string) (io.ReadCloser, error) {
+	var lastErr error
+	for _, voice := range r.Route(language) {
+		synthesize, ok := r.providers[voice.Provider]
+		if !ok {
+			continue
+		}
+
+		if name, ok := voices[voice.Provider]; ok && voice.Voice == "" {
+			voice.Voice = name
+		}
+
+		speech, err := synthesize(ctx, text, voice)
+		if err == nil {
+			return speech, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.Printf("failed to generate speech with %s, trying the next provider: %v", voice.Provider, err)
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
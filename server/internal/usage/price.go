@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Price is in US dollars, keyed by model in a PriceTable.
+type Price struct {
+	InputPerMillion      float64 `json:"input_per_million"`
+	OutputPerMillion     float64 `json:"output_per_million"`
+	PerAudioMinute       float64 `json:"per_audio_minute"`
+	PerMillionCharacters float64 `json:"per_million_characters"`
+}
+
+type PriceTable map[string]Price
+
+var defaultPrices = PriceTable{
+	"gpt-4o-mini-2024-07-18":   {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	"claude-3-5-sonnet-latest": {InputPerMillion: 3, OutputPerMillion: 15},
+	"whisper-1":                {PerAudioMinute: 0.006},
+	"tts-1":                    {PerMillionCharacters: 15},
+	"eleven_multilingual_v2":   {PerMillionCharacters: 300},
+}
+
+func DefaultPriceTable() PriceTable {
+	return defaultPrices
+}
+
+// LoadPriceTable reads a JSON object of model to price, models missing
+// from the file keep their default price.
+func LoadPriceTable(path string) (PriceTable, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var prices PriceTable
+	if err := json.Unmarshal(file, &prices); err != nil {
+		return nil, err
+	}
+
+	table := make(PriceTable, len(defaultPrices)+len(prices))
+	for model, price := range defaultPrices {
+		table[model] = price
+	}
+	for model, price := range prices {
+		table[model] = price
+	}
+
+	return table, nil
+}
+
+// Cost of a record, zero for models without a price.
+func (t PriceTable) Cost(record Record) float64 {
+	price, ok := t[record.Model]
+	if !ok {
+		return 0
+	}
+
+	return float64(record.InputTokens)*price.InputPerMillion/1e6 +
+		float64(record.OutputTokens)*price.OutputPerMillion/1e6 +
+		record.AudioSeconds/60*price.PerAudioMinute +
+		float64(record.Characters)*price.PerMillionCharacters/1e6
+}
@@ -0,0 +1,77 @@
+package usage
+
+import (
+	"context"
+	"sync"
+)
+
+type Kind string
+
+const (
+	KIND_CHAT          Kind = "chat"
+	KIND_TRANSCRIPTION Kind = "transcription"
+	KIND_SPEECH        Kind = "speech"
+)
+
+// Record is what a single upstream call consumed. Only the fields that the
+// kind of call is billed by are set.
+type Record struct {
+	Kind         Kind    `json:"kind"`
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	AudioSeconds float64 `json:"audioSeconds"`
+	Characters   int     `json:"characters"`
+}
+
+// Recorder collects the records of every call made with a context that
+// carries it, so AI clients can report usage without changing their API.
+type Recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, contextKey{}, recorder)
+}
+
+func FromContext(ctx context.Context) *Recorder {
+	recorder, _ := ctx.Value(contextKey{}).(*Recorder)
+	return recorder
+}
+
+// Add records usage on the context's recorder, if there is one.
+func Add(ctx context.Context, record Record) {
+	recorder := FromContext(ctx)
+	if recorder == nil {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.records = append(recorder.records, record)
+}
+
+// Drain returns the collected records and forgets them, so that saving
+// twice does not count a call twice.
+func (r *Recorder) Drain() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := r.records
+	r.records = nil
+
+	return records
+}
+
+// Records returns the records collected so far without forgetting them.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Record(nil), r.records...)
+}
@@ -1,25 +1,30 @@
 package util
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"net/http"
 
-	"github.com/madeindra/mock-interview/server/internal/elevenlab"
+	"github.com/madeindra/mock-interview/server/internal/apierror"
+	"github.com/madeindra/mock-interview/server/internal/language"
 	"github.com/madeindra/mock-interview/server/internal/openai"
+	"github.com/madeindra/mock-interview/server/internal/persona"
+	"github.com/madeindra/mock-interview/server/internal/tts"
 )
 
-func GetChatAssets(ai openai.Client, role string, skills []string, language string) (string, string, error) {
+func GetChatAssets(ai openai.Client, role string, skills []string, pack *language.Pack, interviewer persona.Persona) (string, string, error) {
 	if ai == nil {
 		return "", "", fmt.Errorf("unsupported client")
 	}
 
-	systempPrompt, err := openai.GetSystemPrompt(role, skills, language)
+	systempPrompt, err := pack.RenderSystemPrompt(role, skills, interviewer.Name, interviewer.Style[pack.Language])
 	if err != nil {
 		return "", "", err
 	}
 
-	initialChat, err := openai.GetInitialChat(role, language)
+	initialChat, err := pack.RenderGreeting(role, interviewer.Name, interviewer.Greetings[pack.Language])
 	if err != nil {
 		return "", "", err
 	}
@@ -27,29 +32,29 @@ func GetChatAssets(ai openai.Client, role string, skills []string, language stri
 	return systempPrompt, initialChat, nil
 }
 
-func TranscribeSpeech(ai openai.Client, file io.ReadCloser, filename, language string) (string, error) {
+func TranscribeSpeech(ctx context.Context, ai openai.Client, file io.ReadCloser, filename, language string) (openai.TranscriptResponse, error) {
 	if ai == nil {
-		return "", fmt.Errorf("unsupported client")
+		return openai.TranscriptResponse{}, fmt.Errorf("unsupported client")
 	}
 
-	transcript, err := ai.Transcribe(file, filename, language)
+	transcript, err := ai.Transcribe(ctx, file, filename, language)
 	if err != nil {
-		return "", err
+		return openai.TranscriptResponse{}, err
 	}
 
 	if transcript.Text == "" {
-		return "", fmt.Errorf("empty transcript")
+		return openai.TranscriptResponse{}, fmt.Errorf("empty transcript")
 	}
 
-	return transcript.Text, nil
+	return transcript, nil
 }
 
-func GenerateText(ai openai.Client, entries []openai.ChatMessage) (string, error) {
+func GenerateText(ctx context.Context, ai openai.Client, entries []openai.ChatMessage) (string, error) {
 	if ai == nil {
 		return "", fmt.Errorf("unsupported client")
 	}
 
-	chatCompletion, err := ai.Chat(entries)
+	chatCompletion, err := ai.Chat(ctx, entries)
 	if err != nil {
 		return "", err
 	}
@@ -61,31 +66,33 @@ func GenerateText(ai openai.Client, entries []openai.ChatMessage) (string, error
 	return chatCompletion, nil
 }
 
-func GenerateSpeech(ai openai.Client, el elevenlab.Client, language, text string) (string, error) {
+func GenerateTextStream(ctx context.Context, ai openai.Client, entries []openai.ChatMessage, onDelta func(string) error) (string, error) {
 	if ai == nil {
 		return "", fmt.Errorf("unsupported client")
 	}
 
-	speechInput := SanitizeString(text)
+	chatCompletion, err := ai.ChatStream(ctx, entries, onDelta)
+	if err != nil {
+		return "", err
+	}
 
-	var speech io.ReadCloser
-	if ai.IsSpeechAvailable(language) {
-		tts, err := ai.TextToSpeech(speechInput)
-		if err != nil {
-			return "", err
-		}
+	if chatCompletion == "" {
+		return "", fmt.Errorf("empty chat response")
+	}
+
+	return chatCompletion, nil
+}
 
-		speech = tts
-	} else if el != nil {
-		tts, err := el.TextToSpeech(speechInput)
-		if err != nil {
-			return "", err
-		}
+func GenerateSpeech(ctx context.Context, router *tts.Router, language, text string, voices map[string]string) (string, error) {
+	speech, err := SynthesizeSpeech(ctx, router, language, text, voices)
+	if err != nil {
+		return "", err
+	}
 
-		speech = tts
-	} else {
+	if speech == nil {
 		return "", nil // quietly ignore unsupported language when alternative api not available
 	}
+	defer speech.Close()
 
 	speechByte, err := io.ReadAll(speech)
 	if err != nil {
@@ -95,12 +102,23 @@ func GenerateSpeech(ai openai.Client, el elevenlab.Client, language, text string
 	return base64.StdEncoding.EncodeToString(speechByte), nil
 }
 
-func GenerateSSML(ai openai.Client, text string) (string, error) {
+// SynthesizeSpeech returns the raw audio stream, or nil when no speech
+// engine is routed for the language. Voices are the session's voice per
+// provider.
+func SynthesizeSpeech(ctx context.Context, router *tts.Router, language, text string, voices map[string]string) (io.ReadCloser, error) {
+	if router == nil {
+		return nil, fmt.Errorf("unsupported client")
+	}
+
+	return router.Synthesize(ctx, language, SanitizeString(text), voices)
+}
+
+func GenerateSSML(ctx context.Context, ai openai.Client, text string) (string, error) {
 	if ai == nil {
 		return "", fmt.Errorf("unsupported client")
 	}
 
-	ssml, err := ai.SSML(text)
+	ssml, err := ai.SSML(ctx, text)
 	if err != nil {
 		return "", err
 	}
@@ -120,3 +138,14 @@ func GenerateSSML(ai openai.Client, text string) (string, error) {
 
 	return sanitized, nil
 }
+
+// UpstreamStatus maps a failed AI call to the status code sent to the
+// client, telling transient upstream trouble apart from our own failures.
+func UpstreamStatus(err error) int {
+	switch apierror.KindOf(err) {
+	case apierror.KIND_RATE_LIMIT, apierror.KIND_SERVER, apierror.KIND_UNAVAILABLE, apierror.KIND_QUOTA:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 
@@ -9,10 +10,23 @@ import (
 )
 
 func SendResponse(w http.ResponseWriter, data any, message string, status int) {
-	resp, err := json.Marshal(model.Response{
+	send(w, model.Response{
 		Message: message,
 		Data:    data,
-	})
+	}, status)
+}
+
+// SendError is SendResponse with a machine-readable error code.
+func SendError(w http.ResponseWriter, data any, code, message string, status int) {
+	send(w, model.Response{
+		Message: message,
+		Code:    code,
+		Data:    data,
+	}, status)
+}
+
+func send(w http.ResponseWriter, response model.Response, status int) {
+	resp, err := json.Marshal(response)
 	if err != nil {
 		log.Printf("failed to marshal response: %v", err)
 
@@ -27,3 +41,33 @@ func SendResponse(w http.ResponseWriter, data any, message string, status int) {
 	w.WriteHeader(status)
 	w.Write(resp)
 }
+
+func StartEventStream(w http.ResponseWriter) (http.Flusher, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return flusher, nil
+}
+
+func SendEvent(w http.ResponseWriter, flusher http.Flusher, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+
+	flusher.Flush()
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package vad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Detector is an energy based voice activity detector for 16-bit mono PCM.
+// Audio is fed in as it arrives, the detector buffers the utterance and
+// reports when the speaker has gone quiet long enough to be considered done.
+type Detector struct {
+	sampleRate int
+	threshold  float64
+
+	minSpeechSamples  int
+	minSilenceSamples int
+	maxSamples        int
+	preRollSamples    int
+
+	buffer         []int16
+	speechSamples  int
+	silenceSamples int
+	speaking       bool
+}
+
+const (
+	frameDuration = 0.02 // seconds of audio per energy measurement
+
+	defaultThreshold  = 0.015 // RMS relative to full scale
+	defaultMinSpeech  = 0.3   // seconds
+	defaultMinSilence = 1.2   // seconds
+	defaultMaxLength  = 120.0 // seconds
+	defaultPreRoll    = 0.3   // seconds kept before speech starts
+
+	// MinSampleRate and MaxSampleRate bound the rates a client may stream
+	MinSampleRate = 8000
+	MaxSampleRate = 48000
+)
+
+// ValidSampleRate reports whether the detector supports the sample rate.
+func ValidSampleRate(sampleRate int) bool {
+	return sampleRate >= MinSampleRate && sampleRate <= MaxSampleRate
+}
+
+func NewDetector(sampleRate int) *Detector {
+	return &Detector{
+		sampleRate:        sampleRate,
+		threshold:         defaultThreshold,
+		minSpeechSamples:  int(defaultMinSpeech * float64(sampleRate)),
+		minSilenceSamples: int(defaultMinSilence * float64(sampleRate)),
+		maxSamples:        int(defaultMaxLength * float64(sampleRate)),
+		preRollSamples:    int(defaultPreRoll * float64(sampleRate)),
+	}
+}
+
+// Write feeds little-endian 16-bit PCM and returns true once an utterance
+// is complete. The utterance can then be read with Utterance.
+func (d *Detector) Write(pcm []byte) bool {
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(pcm[i*2:]))
+	}
+
+	// at least one sample per frame, or the loop below never advances
+	frameSize := max(int(frameDuration*float64(d.sampleRate)), 1)
+	done := false
+
+	for start := 0; start < len(samples); start += frameSize {
+		end := min(start+frameSize, len(samples))
+		if d.writeFrame(samples[start:end]) {
+			done = true
+		}
+	}
+
+	return done
+}
+
+func (d *Detector) writeFrame(frame []int16) bool {
+	d.buffer = append(d.buffer, frame...)
+
+	if rms(frame) >= d.threshold {
+		d.speechSamples += len(frame)
+		d.silenceSamples = 0
+
+		if d.speechSamples >= d.minSpeechSamples {
+			d.speaking = true
+		}
+	} else {
+		d.silenceSamples += len(frame)
+
+		if !d.speaking {
+			d.speechSamples = 0
+			d.trimPreRoll()
+		}
+	}
+
+	if !d.speaking {
+		return false
+	}
+
+	return d.silenceSamples >= d.minSilenceSamples || len(d.buffer) >= d.maxSamples
+}
+
+// trimPreRoll drops the silence recorded before the candidate starts
+// speaking, keeping a short lead-in so the first word is not clipped.
+func (d *Detector) trimPreRoll() {
+	if excess := len(d.buffer) - d.preRollSamples; excess > 0 {
+		d.buffer = append(d.buffer[:0], d.buffer[excess:]...)
+	}
+}
+
+// Speaking reports whether speech has been detected in the current utterance.
+func (d *Detector) Speaking() bool {
+	return d.speaking
+}
+
+// Utterance returns the buffered utterance as a WAV file and resets the
+// detector for the next one.
+func (d *Detector) Utterance() []byte {
+	wav := EncodeWAV(d.buffer, d.sampleRate)
+	d.Reset()
+
+	return wav
+}
+
+func (d *Detector) Reset() {
+	d.buffer = nil
+	d.speechSamples = 0
+	d.silenceSamples = 0
+	d.speaking = false
+}
+
+func rms(frame []int16) float64 {
+	if len(frame) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, sample := range frame {
+		normalized := float64(sample) / math.MaxInt16
+		sum += normalized * normalized
+	}
+
+	return math.Sqrt(sum / float64(len(frame)))
+}
+
+// EncodeWAV wraps 16-bit mono PCM samples in a WAV container.
+func EncodeWAV(samples []int16, sampleRate int) []byte {
+	dataSize := uint32(len(samples) * 2)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // mono
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+
+	return buf.Bytes()
+}
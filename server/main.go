@@ -1,12 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/madeindra/mock-interview/server/internal/config"
+	"github.com/madeindra/mock-interview/server/internal/data"
 	"github.com/madeindra/mock-interview/server/internal/handler"
+	"github.com/madeindra/mock-interview/server/internal/provider"
 )
 
 const (
@@ -15,11 +22,81 @@ const (
 	envTTSAPIKey = "ELEVENLAB_API_KEY"
 	envDBPath    = "DB_PATH"
 
+	envLLMProvider = "LLM_PROVIDER"
+	envLLMAPIKey   = "LLM_API_KEY"
+	envLLMBaseURL  = "LLM_BASE_URL"
+	envLLMModel    = "LLM_MODEL"
+
+	envSpeechProvider           = "SPEECH_PROVIDER"
+	envSpeechAPIKey             = "SPEECH_API_KEY"
+	envSpeechBaseURL            = "SPEECH_BASE_URL"
+	envSpeechTranscriptionModel = "SPEECH_TRANSCRIPTION_MODEL"
+	envSpeechTTSModel           = "SPEECH_TTS_MODEL"
+
+	envFakeScript = "FAKE_AI_SCRIPT"
+
+	envTTSRoutes = "TTS_ROUTES"
+	envPersonas  = "PERSONAS"
+
+	envLanguagePacks = "LANGUAGE_PACKS_DIR"
+	envRubric        = "RUBRIC"
+
+	envAITimeout  = "AI_TIMEOUT"
+	envTTSTimeout = "TTS_TIMEOUT"
+
+	envRetryMaxAttempts = "RETRY_MAX_ATTEMPTS"
+	envRetryBaseDelay   = "RETRY_BASE_DELAY"
+	envRetryMaxDelay    = "RETRY_MAX_DELAY"
+	envBreakerThreshold = "BREAKER_THRESHOLD"
+	envBreakerCooldown  = "BREAKER_COOLDOWN"
+
+	envHistoryMaxTokens      = "HISTORY_MAX_TOKENS"
+	envHistoryRecentMessages = "HISTORY_RECENT_MESSAGES"
+
+	envPriceTable  = "PRICE_TABLE"
+	envAdminAPIKey = "ADMIN_API_KEY"
+
+	envBudgetMaxTurns        = "BUDGET_MAX_TURNS"
+	envBudgetMaxAudioSeconds = "BUDGET_MAX_AUDIO_SECONDS"
+	envBudgetMaxTokens       = "BUDGET_MAX_TOKENS"
+	envBudgetDailySpend      = "BUDGET_DAILY_SPEND"
+	envClientAPIKeys         = "CLIENT_API_KEYS"
+	envTrustedProxies        = "TRUSTED_PROXIES"
+
+	envSessionTTL = "SESSION_TTL"
+
+	envBlobStore         = "BLOB_STORE"
+	envBlobDir           = "BLOB_DIR"
+	envS3Endpoint        = "S3_ENDPOINT"
+	envS3Region          = "S3_REGION"
+	envS3Bucket          = "S3_BUCKET"
+	envS3AccessKeyID     = "S3_ACCESS_KEY_ID"
+	envS3SecretAccessKey = "S3_SECRET_ACCESS_KEY"
+	envAudioURLs         = "AUDIO_URLS"
+
 	envCORSOrigins = "CORS_ALLOWED_ORIGINS"
 	envCORSMethods = "CORS_ALLOWED_METHODS"
 	envCORSHeaders = "CORS_ALLOWED_HEADERS"
 
-	defaultPort = "8080"
+	defaultPort        = "8080"
+	defaultDBPath      = "./app.db"
+	defaultLLMProvider = "openai"
+	defaultAITimeout   = 60 * time.Second
+	defaultTTSTimeout  = 60 * time.Second
+
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 8 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+
+	defaultHistoryMaxTokens      = 8000
+	defaultHistoryRecentMessages = 8
+
+	defaultBlobDir  = "./blobs"
+	defaultS3Region = "us-east-1"
+
+	migrateUsage = "usage: migrate up | down [steps] | status"
 )
 
 var (
@@ -29,6 +106,14 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(config.GetString(envDBPath, defaultDBPath), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	cfg, err := initConfig()
 	if err != nil {
 		log.Fatal(err)
@@ -52,15 +137,143 @@ func initConfig() (config.AppConfig, error) {
 		Port:        config.GetString(envPort, defaultPort),
 		APIKey:      config.GetString(envAPIKey, ""),
 		TTSAPIKey:   config.GetString(envTTSAPIKey, ""),
-		DBPath:      config.GetString(envDBPath, "./app.db"),
+		DBPath:      config.GetString(envDBPath, defaultDBPath),
+		LLMProvider: config.GetString(envLLMProvider, defaultLLMProvider),
+		LLMAPIKey:   config.GetString(envLLMAPIKey, ""),
+		LLMBaseURL:  config.GetString(envLLMBaseURL, ""),
+		LLMModel:    config.GetString(envLLMModel, ""),
+
+		SpeechProvider:           config.GetString(envSpeechProvider, ""),
+		SpeechAPIKey:             config.GetString(envSpeechAPIKey, ""),
+		SpeechBaseURL:            config.GetString(envSpeechBaseURL, ""),
+		SpeechTranscriptionModel: config.GetString(envSpeechTranscriptionModel, ""),
+		SpeechTTSModel:           config.GetString(envSpeechTTSModel, ""),
+
+		FakeScriptPath: config.GetString(envFakeScript, ""),
+
+		TTSRoutesPath: config.GetString(envTTSRoutes, ""),
+		PersonasPath:  config.GetString(envPersonas, ""),
+
+		LanguagePacksPath: config.GetString(envLanguagePacks, ""),
+		RubricPath:        config.GetString(envRubric, ""),
+
+		AITimeout:  config.GetDuration(envAITimeout, defaultAITimeout),
+		TTSTimeout: config.GetDuration(envTTSTimeout, defaultTTSTimeout),
+
+		RetryMaxAttempts: config.GetInt(envRetryMaxAttempts, defaultRetryMaxAttempts),
+		RetryBaseDelay:   config.GetDuration(envRetryBaseDelay, defaultRetryBaseDelay),
+		RetryMaxDelay:    config.GetDuration(envRetryMaxDelay, defaultRetryMaxDelay),
+		BreakerThreshold: config.GetInt(envBreakerThreshold, defaultBreakerThreshold),
+		BreakerCooldown:  config.GetDuration(envBreakerCooldown, defaultBreakerCooldown),
+
+		HistoryMaxTokens:      config.GetInt(envHistoryMaxTokens, defaultHistoryMaxTokens),
+		HistoryRecentMessages: config.GetInt(envHistoryRecentMessages, defaultHistoryRecentMessages),
+
+		PriceTablePath: config.GetString(envPriceTable, ""),
+		AdminAPIKey:    config.GetString(envAdminAPIKey, ""),
+
+		BudgetMaxTurns:        config.GetInt(envBudgetMaxTurns, 0),
+		BudgetMaxAudioSeconds: config.GetFloat(envBudgetMaxAudioSeconds, 0),
+		BudgetMaxTokens:       config.GetInt(envBudgetMaxTokens, 0),
+		BudgetDailySpend:      config.GetFloat(envBudgetDailySpend, 0),
+		ClientAPIKeys:         config.GetStrings(envClientAPIKeys, nil),
+		TrustedProxies:        config.GetStrings(envTrustedProxies, nil),
+
+		SessionTTL: config.GetDuration(envSessionTTL, 0),
+
+		BlobStore:         config.GetString(envBlobStore, provider.BLOB_STORE_LOCAL),
+		BlobDir:           config.GetString(envBlobDir, defaultBlobDir),
+		S3Endpoint:        config.GetString(envS3Endpoint, ""),
+		S3Region:          config.GetString(envS3Region, defaultS3Region),
+		S3Bucket:          config.GetString(envS3Bucket, ""),
+		S3AccessKeyID:     config.GetString(envS3AccessKeyID, ""),
+		S3SecretAccessKey: config.GetString(envS3SecretAccessKey, ""),
+		AudioURLs:         config.GetBool(envAudioURLs, false),
+
 		CORSOrigins: config.GetStrings(envCORSOrigins, defaultCORSOrigin),
 		CORSMethods: config.GetStrings(envCORSMethods, defaultCORSMethods),
 		CORSHeaders: config.GetStrings(envCORSHeaders, defaultCORSHeaders),
 	}
 
-	if cfg.APIKey == "" {
-		return config.AppConfig{}, fmt.Errorf("API Key and DB URI is needed")
+	// the OpenAI key is only needed when OpenAI serves the chat or the speech
+	usesOpenAI := cfg.LLMProvider == provider.PROVIDER_OPENAI || provider.SpeechProvider(cfg) == provider.PROVIDER_OPENAI
+	if cfg.APIKey == "" && usesOpenAI {
+		return config.AppConfig{}, fmt.Errorf("%s is needed when %s or %s is %s", envAPIKey, envLLMProvider, envSpeechProvider, provider.PROVIDER_OPENAI)
 	}
 
 	return cfg, nil
 }
+
+// runMigrate manages the schema of the database at dbPath without starting
+// the server.
+func runMigrate(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := data.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		migrated, err := db.Migrate()
+		for _, migration := range migrated {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(migrated) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number")
+			}
+		}
+
+		rolledBack, err := db.Rollback(steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(rolledBack) == 0 {
+			fmt.Println("no applied migrations")
+		}
+	case "status":
+		states, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, state := range states {
+			applied := "pending"
+			switch {
+			case state.Unknown:
+				applied = "unknown to this build, applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			case state.AppliedAt != nil:
+				applied = state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", state.Version, state.Name, applied)
+		}
+
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}